@@ -0,0 +1,79 @@
+// Command errdocs rewrites the error code table in docs/API.md from the codes
+// registered with apperror. Run it after adding or changing a code:
+//
+//	go run ./cmd/errdocs
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	// Everything the server links registers its codes on import.
+	_ "github.com/aarondever/go-gin-template/internal/router"
+)
+
+const (
+	beginMarker = "<!-- error-codes:begin -->"
+	endMarker   = "<!-- error-codes:end -->"
+)
+
+func main() {
+	path := flag.String("file", "docs/API.md", "Markdown file holding the table between the error-codes markers")
+	check := flag.Bool("check", false, "fail if the file is out of date instead of rewriting it")
+	flag.Parse()
+
+	doc, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	updated, err := replaceTable(doc, table(e.Definitions()))
+	if err != nil {
+		log.Fatalf("%s: %v", *path, err)
+	}
+	if bytes.Equal(doc, updated) {
+		return
+	}
+	if *check {
+		log.Fatalf("%s: error code table is out of date; run go run ./cmd/errdocs", *path)
+	}
+	if err := os.WriteFile(*path, updated, 0o644); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// table renders defs as a Markdown table, one row per code.
+func table(defs []e.Definition) string {
+	var b strings.Builder
+	b.WriteString("| Code | HTTP | Retryable | When |\n| --- | --- | --- | --- |\n")
+	for _, d := range defs {
+		retryable := "no"
+		if d.Retryable {
+			retryable = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | %d | %s | %s |\n", d.Code, d.Status, retryable, d.Doc)
+	}
+	return b.String()
+}
+
+// replaceTable swaps what lies between the markers in doc for table.
+func replaceTable(doc []byte, table string) ([]byte, error) {
+	before, rest, ok := bytes.Cut(doc, []byte(beginMarker))
+	if !ok {
+		return nil, errors.New("no " + beginMarker + " marker")
+	}
+	_, after, ok := bytes.Cut(rest, []byte(endMarker))
+	if !ok {
+		return nil, errors.New("no " + endMarker + " marker")
+	}
+	var out bytes.Buffer
+	out.Write(before)
+	out.WriteString(beginMarker + "\n" + table + endMarker)
+	out.Write(after)
+	return out.Bytes(), nil
+}
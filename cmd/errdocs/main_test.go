@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+)
+
+// The API reference lists exactly the codes registered.
+func TestAPIReferenceUpToDate(t *testing.T) {
+	doc, err := os.ReadFile("../../docs/API.md")
+	if err != nil {
+		t.Fatalf("read API reference: %v", err)
+	}
+	updated, err := replaceTable(doc, table(e.Definitions()))
+	if err != nil {
+		t.Fatalf("replaceTable() error = %v", err)
+	}
+	if string(updated) != string(doc) {
+		t.Error("docs/API.md error code table is out of date; run go run ./cmd/errdocs")
+	}
+}
+
+func TestReplaceTableNeedsMarkers(t *testing.T) {
+	for _, doc := range []string{"no markers", beginMarker + " but no end"} {
+		if _, err := replaceTable([]byte(doc), "table"); err == nil {
+			t.Errorf("replaceTable(%q) error = nil, want one", doc)
+		}
+	}
+}
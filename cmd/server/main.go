@@ -13,9 +13,14 @@ import (
 	"time"
 
 	"github.com/aarondever/go-gin-template/config"
+	"github.com/aarondever/go-gin-template/internal/auth"
 	"github.com/aarondever/go-gin-template/internal/database"
 	"github.com/aarondever/go-gin-template/internal/handler"
+	"github.com/aarondever/go-gin-template/internal/job"
 	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/mailer"
+	"github.com/aarondever/go-gin-template/internal/middleware"
+	"github.com/aarondever/go-gin-template/internal/ratelimit"
 	"github.com/aarondever/go-gin-template/internal/repository"
 	"github.com/aarondever/go-gin-template/internal/router"
 	"github.com/aarondever/go-gin-template/internal/service"
@@ -41,7 +46,7 @@ func run() (err error) {
 	}
 
 	// Initialize logger
-	logger.Init(cfg.Log, logger.WithTrace())
+	logger.Init(cfg.Log, logger.WithTrace(), middleware.LogRequestID())
 
 	// Initialize telemetry
 	otelShutdown, err := telemetry.Init(context.Background(), cfg.OTEL)
@@ -75,15 +80,86 @@ func run() (err error) {
 
 	// Initialize repository
 	repo := repository.New(db.DB())
+	apiKeyRepo := repository.NewAPIKeyRepository(db.DB())
+	roleRepo := repository.NewRoleRepository(db.DB())
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB())
+	userTokenRepo := repository.NewUserTokenRepository(db.DB())
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db.DB())
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db.DB())
+	idempotencyRepo := repository.NewIdempotencyRepository(db.DB())
+	txManager := database.NewTxManager(db.DB())
+
+	// Initialize authentication
+	jwtVerifier, err := auth.NewJWTVerifier(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+	tokenIssuer, err := auth.NewTokenIssuer(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+	passwords, err := auth.NewPasswordHasher(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+	mfaBox, err := auth.NewSecretBox(cfg.Auth.MFAEncryptionKey)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+
+	// Initialize mail
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	mailTemplates, err := mailer.NewTemplates(cfg.Mail.DefaultLocale)
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer: %w", err)
+	}
 
 	// Initialize service
-	svc := service.New(repo)
+	authorizer := service.NewAuthorizer(roleRepo)
+	svc := service.New(repo, txManager, passwords)
+	authSvc := service.NewAuthService(
+		repo, refreshTokenRepo, userTokenRepo, recoveryCodeRepo, loginAttemptRepo,
+		txManager, passwords, tokenIssuer, mfaBox, cfg.Auth,
+	)
+	mfaSvc := service.NewMFAService(repo, recoveryCodeRepo, loginAttemptRepo, txManager, mfaBox, cfg.Auth)
+	accountSvc := service.NewAccountService(
+		repo, userTokenRepo, refreshTokenRepo, txManager, passwords, mail, mailTemplates, cfg.Auth, cfg.Mail,
+	)
+	defer accountSvc.Close()
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, authorizer, cfg.Auth.APIKeyPrefix)
+	defer apiKeySvc.Close()
 
 	// Initialize handler
 	h := handler.New(svc)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeySvc)
+	authHandler := handler.NewAuthHandler(authSvc)
+	accountHandler := handler.NewAccountHandler(accountSvc)
+	mfaHandler := handler.NewMFAHandler(mfaSvc)
+
+	// Start background jobs; they stop before the database closes.
+	jobCtx, stopJobs := context.WithCancel(ctx)
+	waitJobs := job.Every(jobCtx, "purge expired tokens", cfg.Auth.TokenPurgeEvery, func(ctx context.Context) error {
+		_, refreshErr := authSvc.PurgeExpired(ctx)
+		_, accountErr := accountSvc.PurgeExpired(ctx)
+		_, idempotencyErr := idempotencyRepo.DeleteExpired(ctx, time.Now())
+		return errors.Join(refreshErr, accountErr, idempotencyErr)
+	})
+	defer func() {
+		stopJobs()
+		waitJobs()
+	}()
 
 	// Setup router
-	r := router.SetupRouter(cfg, h)
+	r, err := router.SetupRouter(cfg, h, apiKeyHandler, authHandler, accountHandler, mfaHandler, []auth.Authenticator{
+		jwtVerifier,
+		auth.NewAPIKeyAuthenticator(apiKeySvc),
+	}, authorizer, ratelimit.NewMemoryStore(), idempotencyRepo)
+	if err != nil {
+		return fmt.Errorf("failed to set up router: %w", err)
+	}
 
 	// Start HTTP server
 	srv := &http.Server{
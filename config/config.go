@@ -10,17 +10,44 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
-	Log    LogConfig
-	OTEL   OTELConfig
+	Server      ServerConfig
+	DB          DBConfig
+	Log         LogConfig
+	OTEL        OTELConfig
+	Auth        AuthConfig
+	Mail        MailConfig
+	RateLimit   RateLimitConfig
+	CORS        CORSConfig
+	Idempotency IdempotencyConfig
+	Concurrency ConcurrencyConfig
 }
 
 type ServerConfig struct {
-	Port         int           `env:"SERVER_PORT" envDefault:"8080"`
-	Mode         string        `env:"SERVER_MODE" envDefault:"release"` // "debug", or "release"
-	ReadTimeout  time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"30s"`
-	WriteTimeout time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"30s"`
+	Port           int           `env:"SERVER_PORT" envDefault:"8080"`
+	Mode           string        `env:"SERVER_MODE" envDefault:"release"` // "debug", or "release"
+	ReadTimeout    time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"30s"`
+	WriteTimeout   time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"30s"`
+	RequestTimeout time.Duration `env:"SERVER_REQUEST_TIMEOUT" envDefault:"10s"`     // deadline on each request's context; below the write timeout
+	MaxBodyBytes   int64         `env:"SERVER_MAX_BODY_BYTES" envDefault:"1048576"`  // routes may set lower limits
+	CompressMin    int           `env:"SERVER_COMPRESS_MIN_BYTES" envDefault:"1024"` // smaller responses are sent as they are
+	TrustedProxies []string      `env:"SERVER_TRUSTED_PROXIES" envSeparator:","`     // IPs or CIDRs whose X-Forwarded-For is believed
+	ErrorFormat    string        `env:"SERVER_ERROR_FORMAT" envDefault:"envelope"`   // "envelope" or "problem" (RFC 9457); clients may ask for either
+	LegacyDetails  bool          `env:"SERVER_LEGACY_VALIDATION_DETAILS"`            // also send validation failures as the old field→rule details map
+	DefaultLocale  string        `env:"SERVER_DEFAULT_LOCALE" envDefault:"en"`       // language of error messages when Accept-Language names none supported
+	StrictJSON     bool          `env:"SERVER_STRICT_JSON"`                          // reject request bodies with fields the endpoint does not take
+
+	// Deadlines replacing the request timeout on a route or group, written
+	// "/v1/auth=5s,/v1/users/:userID/mfa=3s"; the longest matching path wins.
+	RouteTimeouts map[string]time.Duration `env:"SERVER_ROUTE_TIMEOUTS" envKeyValSeparator:"="`
+
+	// Security headers; "off" leaves a header out.
+	HSTSMaxAge            time.Duration `env:"SERVER_HSTS_MAX_AGE" envDefault:"8760h"` // 0 disables HSTS
+	HSTSIncludeSubdomains bool          `env:"SERVER_HSTS_INCLUDE_SUBDOMAINS" envDefault:"true"`
+	HSTSPreload           bool          `env:"SERVER_HSTS_PRELOAD"`
+	ContentSecurityPolicy string        `env:"SERVER_CSP" envDefault:"default-src 'none'; frame-ancestors 'none'"` // {nonce} is replaced per request
+	ReferrerPolicy        string        `env:"SERVER_REFERRER_POLICY" envDefault:"no-referrer"`
+	FrameOptions          string        `env:"SERVER_FRAME_OPTIONS" envDefault:"DENY"`
+	PermissionsPolicy     string        `env:"SERVER_PERMISSIONS_POLICY" envDefault:"camera=(), microphone=(), geolocation=(), payment=()"`
 }
 
 type DBConfig struct {
@@ -46,6 +73,84 @@ type OTELConfig struct {
 	SampleRatio float64 `env:"OTEL_TRACES_SAMPLER_ARG" envDefault:"1"`
 }
 
+type AuthConfig struct {
+	JWTAlgorithm      string        `env:"AUTH_JWT_ALGORITHM" envDefault:"HS256"` // "HS256", "RS256" or "EdDSA"
+	JWTSecret         string        `env:"AUTH_JWT_SECRET,unset"`                 // HS256 only
+	JWTPublicKeyFile  string        `env:"AUTH_JWT_PUBLIC_KEY_FILE"`              // PEM; RS256 and EdDSA
+	JWKSFile          string        `env:"AUTH_JWT_JWKS_FILE"`                    // local JWKS; RS256 and EdDSA
+	JWTPrivateKeyFile string        `env:"AUTH_JWT_PRIVATE_KEY_FILE"`             // PEM; signs issued tokens for RS256 and EdDSA
+	JWTKeyID          string        `env:"AUTH_JWT_KEY_ID"`                       // kid header on issued tokens
+	JWTIssuer         string        `env:"AUTH_JWT_ISSUER"`
+	JWTAudience       string        `env:"AUTH_JWT_AUDIENCE"`
+	ClockSkew         time.Duration `env:"AUTH_CLOCK_SKEW" envDefault:"30s"`
+	AccessTokenTTL    time.Duration `env:"AUTH_ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL   time.Duration `env:"AUTH_REFRESH_TOKEN_TTL" envDefault:"720h"`
+	TokenPurgeEvery   time.Duration `env:"AUTH_TOKEN_PURGE_INTERVAL" envDefault:"1h"` // how often expired tokens are deleted
+	VerifyEmailTTL    time.Duration `env:"AUTH_VERIFY_EMAIL_TTL" envDefault:"48h"`
+	PasswordResetTTL  time.Duration `env:"AUTH_PASSWORD_RESET_TTL" envDefault:"1h"`
+	MFAEncryptionKey  string        `env:"AUTH_MFA_ENCRYPTION_KEY,unset"`               // base64, 32 bytes; encrypts TOTP secrets
+	MFAIssuer         string        `env:"AUTH_MFA_ISSUER" envDefault:"go-gin-service"` // names the service in authenticator apps
+	MFAChallengeTTL   time.Duration `env:"AUTH_MFA_CHALLENGE_TTL" envDefault:"5m"`
+	AccountLockAfter  int           `env:"AUTH_LOCKOUT_ACCOUNT_THRESHOLD" envDefault:"5"` // failures before an account locks
+	IPLockAfter       int           `env:"AUTH_LOCKOUT_IP_THRESHOLD" envDefault:"20"`     // failures before a client IP locks
+	LockoutDuration   time.Duration `env:"AUTH_LOCKOUT_DURATION" envDefault:"1m"`         // first lock; doubles with each further failure
+	MaxLockout        time.Duration `env:"AUTH_LOCKOUT_MAX_DURATION" envDefault:"1h"`
+	LockoutResetAfter time.Duration `env:"AUTH_LOCKOUT_RESET_AFTER" envDefault:"24h"` // failures older than this are forgotten
+	APIKeyPrefix      string        `env:"AUTH_API_KEY_PREFIX" envDefault:"gk"`       // visible start of every generated key
+	Argon2Memory      uint32        `env:"AUTH_ARGON2_MEMORY" envDefault:"65536"`     // KiB per hash
+	Argon2Iterations  uint32        `env:"AUTH_ARGON2_ITERATIONS" envDefault:"3"`
+	Argon2Parallelism uint8         `env:"AUTH_ARGON2_PARALLELISM" envDefault:"2"`
+}
+
+type MailConfig struct {
+	Driver        string `env:"MAIL_DRIVER" envDefault:"file"` // "smtp" or "file"
+	From          string `env:"MAIL_FROM" envDefault:"no-reply@localhost"`
+	FileDir       string `env:"MAIL_FILE_DIR" envDefault:"tmp/mail"` // file driver only
+	SMTPHost      string `env:"MAIL_SMTP_HOST"`
+	SMTPPort      int    `env:"MAIL_SMTP_PORT" envDefault:"587"`
+	SMTPUsername  string `env:"MAIL_SMTP_USERNAME"`
+	SMTPPassword  string `env:"MAIL_SMTP_PASSWORD,unset"`
+	LinkBaseURL   string `env:"MAIL_LINK_BASE_URL" envDefault:"http://localhost:8080"` // front end that handles emailed links
+	DefaultLocale string `env:"MAIL_DEFAULT_LOCALE" envDefault:"en"`
+}
+
+// RateLimitConfig holds limits written as "<count>/<period>", e.g. "10/1m".
+type RateLimitConfig struct {
+	Enabled bool   `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	Auth    string `env:"RATE_LIMIT_AUTH" envDefault:"20/1m"` // per client IP, across /v1/auth
+	API     string `env:"RATE_LIMIT_API" envDefault:"600/1m"` // per user or API key, across authenticated routes
+}
+
+// CORSConfig is the cross-origin policy. With no allowed origins, CORS is off
+// and browsers keep to same-origin requests.
+type CORSConfig struct {
+	AllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" envSeparator:","` // exact, "*", "https://*.example.com" or "regex:<pattern>"
+	AllowedMethods   []string      `env:"CORS_ALLOWED_METHODS" envSeparator:"," envDefault:"GET,HEAD,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" envSeparator:"," envDefault:"Authorization,Content-Type,Accept-Language,X-API-Key,X-Request-ID,Idempotency-Key,If-None-Match"`
+	ExposedHeaders   []string      `env:"CORS_EXPOSED_HEADERS" envSeparator:"," envDefault:"X-Request-ID,Retry-After,RateLimit-Policy,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Idempotent-Replayed,ETag"`
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" envDefault:"10m"` // how long browsers may cache a preflight
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS"`        // cookies and HTTP auth; exact origins only
+}
+
+// IdempotencyConfig governs Idempotency-Key handling on POST and PATCH.
+type IdempotencyConfig struct {
+	TTL         time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`         // how long a response is replayed
+	LockTimeout time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT" envDefault:"1m"` // after this a retry may take over an unfinished key; above the request timeout
+}
+
+// ConcurrencyConfig caps the requests handled at once; the rest wait briefly
+// and are then shed with UNAVAILABLE. A limit of 0 turns the cap off.
+type ConcurrencyConfig struct {
+	Limit         int           `env:"CONCURRENCY_LIMIT" envDefault:"100"` // fixed cap, or the starting one when adaptive
+	Adaptive      bool          `env:"CONCURRENCY_ADAPTIVE"`               // adjust the cap to keep latency under the target
+	MinLimit      int           `env:"CONCURRENCY_MIN_LIMIT" envDefault:"10"`
+	MaxLimit      int           `env:"CONCURRENCY_MAX_LIMIT" envDefault:"1000"`
+	TargetLatency time.Duration `env:"CONCURRENCY_TARGET_LATENCY" envDefault:"500ms"`
+	QueueSize     int           `env:"CONCURRENCY_QUEUE_SIZE" envDefault:"100"`      // requests that may wait for a slot
+	HighQueueSize int           `env:"CONCURRENCY_HIGH_QUEUE_SIZE" envDefault:"10"`  // health checks that may wait, ahead of the rest
+	QueueTimeout  time.Duration `env:"CONCURRENCY_QUEUE_TIMEOUT" envDefault:"200ms"` // how long one may wait
+}
+
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		if !os.IsNotExist(err) {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -10,10 +11,33 @@ import (
 // envKeys is every variable Load reads. Tests clear all of them so a developer's
 // shell environment cannot leak into the results.
 var envKeys = []string{
-	"SERVER_PORT", "SERVER_MODE", "SERVER_READ_TIMEOUT", "SERVER_WRITE_TIMEOUT",
+	"SERVER_PORT", "SERVER_MODE", "SERVER_READ_TIMEOUT", "SERVER_WRITE_TIMEOUT", "SERVER_REQUEST_TIMEOUT",
+	"SERVER_MAX_BODY_BYTES", "SERVER_COMPRESS_MIN_BYTES", "SERVER_TRUSTED_PROXIES",
+	"SERVER_ERROR_FORMAT", "SERVER_LEGACY_VALIDATION_DETAILS", "SERVER_DEFAULT_LOCALE", "SERVER_STRICT_JSON", "SERVER_ROUTE_TIMEOUTS",
+	"SERVER_HSTS_MAX_AGE", "SERVER_HSTS_INCLUDE_SUBDOMAINS", "SERVER_HSTS_PRELOAD", "SERVER_CSP",
+	"SERVER_REFERRER_POLICY", "SERVER_FRAME_OPTIONS", "SERVER_PERMISSIONS_POLICY",
 	"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE",
 	"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME",
 	"LOG_LEVEL", "LOG_FORMAT",
+	"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_SERVICE_NAME", "OTEL_TRACES_SAMPLER_ARG",
+	"AUTH_JWT_ALGORITHM", "AUTH_JWT_SECRET", "AUTH_JWT_PUBLIC_KEY_FILE", "AUTH_JWT_JWKS_FILE",
+	"AUTH_JWT_PRIVATE_KEY_FILE", "AUTH_JWT_KEY_ID", "AUTH_JWT_ISSUER", "AUTH_JWT_AUDIENCE",
+	"AUTH_CLOCK_SKEW", "AUTH_ACCESS_TOKEN_TTL", "AUTH_REFRESH_TOKEN_TTL",
+	"AUTH_TOKEN_PURGE_INTERVAL", "AUTH_API_KEY_PREFIX",
+	"AUTH_ARGON2_MEMORY", "AUTH_ARGON2_ITERATIONS", "AUTH_ARGON2_PARALLELISM",
+	"AUTH_VERIFY_EMAIL_TTL", "AUTH_PASSWORD_RESET_TTL",
+	"AUTH_MFA_ENCRYPTION_KEY", "AUTH_MFA_ISSUER", "AUTH_MFA_CHALLENGE_TTL",
+	"AUTH_LOCKOUT_ACCOUNT_THRESHOLD", "AUTH_LOCKOUT_IP_THRESHOLD", "AUTH_LOCKOUT_DURATION",
+	"AUTH_LOCKOUT_MAX_DURATION", "AUTH_LOCKOUT_RESET_AFTER",
+	"MAIL_DRIVER", "MAIL_FROM", "MAIL_FILE_DIR", "MAIL_SMTP_HOST", "MAIL_SMTP_PORT",
+	"MAIL_SMTP_USERNAME", "MAIL_SMTP_PASSWORD", "MAIL_LINK_BASE_URL", "MAIL_DEFAULT_LOCALE",
+	"RATE_LIMIT_ENABLED", "RATE_LIMIT_AUTH", "RATE_LIMIT_API",
+	"CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_METHODS", "CORS_ALLOWED_HEADERS", "CORS_EXPOSED_HEADERS",
+	"CORS_MAX_AGE", "CORS_ALLOW_CREDENTIALS",
+	"IDEMPOTENCY_TTL", "IDEMPOTENCY_LOCK_TIMEOUT",
+	"CONCURRENCY_LIMIT", "CONCURRENCY_ADAPTIVE", "CONCURRENCY_MIN_LIMIT", "CONCURRENCY_MAX_LIMIT",
+	"CONCURRENCY_TARGET_LATENCY", "CONCURRENCY_QUEUE_SIZE", "CONCURRENCY_HIGH_QUEUE_SIZE",
+	"CONCURRENCY_QUEUE_TIMEOUT",
 }
 
 // isolate moves the test into an empty directory (so no .env is picked up) and
@@ -52,10 +76,22 @@ func TestLoadDefaults(t *testing.T) {
 
 	want := Config{
 		Server: ServerConfig{
-			Port:         8080,
-			Mode:         "release",
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 30 * time.Second,
+			Port:           8080,
+			Mode:           "release",
+			ReadTimeout:    30 * time.Second,
+			WriteTimeout:   30 * time.Second,
+			RequestTimeout: 10 * time.Second,
+			MaxBodyBytes:   1 << 20,
+			CompressMin:    1024,
+			ErrorFormat:    "envelope",
+			DefaultLocale:  "en",
+
+			HSTSMaxAge:            8760 * time.Hour,
+			HSTSIncludeSubdomains: true,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			ReferrerPolicy:        "no-referrer",
+			FrameOptions:          "DENY",
+			PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=()",
 		},
 		DB: DBConfig{
 			Host:            "localhost",
@@ -68,9 +104,53 @@ func TestLoadDefaults(t *testing.T) {
 			MaxIdleConns:    5,
 			ConnMaxLifetime: 5 * time.Minute,
 		},
-		Log: LogConfig{Level: "info", Format: "json"},
+		Log:  LogConfig{Level: "info", Format: "json"},
+		OTEL: OTELConfig{ServiceName: "go-gin-service", SampleRatio: 1},
+		Auth: AuthConfig{
+			JWTAlgorithm:      "HS256",
+			ClockSkew:         30 * time.Second,
+			AccessTokenTTL:    15 * time.Minute,
+			RefreshTokenTTL:   720 * time.Hour,
+			TokenPurgeEvery:   time.Hour,
+			VerifyEmailTTL:    48 * time.Hour,
+			PasswordResetTTL:  time.Hour,
+			MFAIssuer:         "go-gin-service",
+			MFAChallengeTTL:   5 * time.Minute,
+			AccountLockAfter:  5,
+			IPLockAfter:       20,
+			LockoutDuration:   time.Minute,
+			MaxLockout:        time.Hour,
+			LockoutResetAfter: 24 * time.Hour,
+			APIKeyPrefix:      "gk",
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+		},
+		Mail: MailConfig{
+			Driver:        "file",
+			From:          "no-reply@localhost",
+			FileDir:       "tmp/mail",
+			SMTPPort:      587,
+			LinkBaseURL:   "http://localhost:8080",
+			DefaultLocale: "en",
+		},
+		RateLimit: RateLimitConfig{Enabled: true, Auth: "20/1m", API: "600/1m"},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Accept-Language", "X-API-Key", "X-Request-ID", "Idempotency-Key", "If-None-Match"},
+			ExposedHeaders: []string{
+				"X-Request-ID", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+				"Idempotent-Replayed", "ETag",
+			},
+			MaxAge: 10 * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour, LockTimeout: time.Minute},
+		Concurrency: ConcurrencyConfig{
+			Limit: 100, MinLimit: 10, MaxLimit: 1000, TargetLatency: 500 * time.Millisecond,
+			QueueSize: 100, HighQueueSize: 10, QueueTimeout: 200 * time.Millisecond,
+		},
 	}
-	if *cfg != want {
+	if !reflect.DeepEqual(*cfg, want) {
 		t.Errorf("Load() = %+v, want %+v", *cfg, want)
 	}
 }
@@ -83,6 +163,22 @@ func TestLoadFromEnv(t *testing.T) {
 	t.Setenv("SERVER_MODE", "debug")
 	t.Setenv("SERVER_READ_TIMEOUT", "5s")
 	t.Setenv("SERVER_WRITE_TIMEOUT", "1m")
+	t.Setenv("SERVER_REQUEST_TIMEOUT", "45s")
+	t.Setenv("SERVER_MAX_BODY_BYTES", "65536")
+	t.Setenv("SERVER_COMPRESS_MIN_BYTES", "256")
+	t.Setenv("SERVER_HSTS_MAX_AGE", "0")
+	t.Setenv("SERVER_HSTS_INCLUDE_SUBDOMAINS", "false")
+	t.Setenv("SERVER_HSTS_PRELOAD", "true")
+	t.Setenv("SERVER_CSP", "script-src 'nonce-{nonce}'")
+	t.Setenv("SERVER_REFERRER_POLICY", "same-origin")
+	t.Setenv("SERVER_FRAME_OPTIONS", "SAMEORIGIN")
+	t.Setenv("SERVER_PERMISSIONS_POLICY", "camera=()")
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.0/8,192.168.1.1")
+	t.Setenv("SERVER_ERROR_FORMAT", "problem")
+	t.Setenv("SERVER_LEGACY_VALIDATION_DETAILS", "true")
+	t.Setenv("SERVER_DEFAULT_LOCALE", "es")
+	t.Setenv("SERVER_STRICT_JSON", "true")
+	t.Setenv("SERVER_ROUTE_TIMEOUTS", "/v1/auth=5s,/v1/users/:userID/mfa=3s")
 	t.Setenv("DB_HOST", "db.internal")
 	t.Setenv("DB_PORT", "6543")
 	t.Setenv("DB_SSLMODE", "require")
@@ -91,6 +187,60 @@ func TestLoadFromEnv(t *testing.T) {
 	t.Setenv("DB_CONN_MAX_LIFETIME", "90s")
 	t.Setenv("LOG_LEVEL", "debug")
 	t.Setenv("LOG_FORMAT", "text")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_SERVICE_NAME", "users-api")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("AUTH_JWT_ALGORITHM", "EdDSA")
+	t.Setenv("AUTH_JWT_JWKS_FILE", "/etc/jwks.json")
+	t.Setenv("AUTH_JWT_ISSUER", "https://issuer.example.com")
+	t.Setenv("AUTH_JWT_AUDIENCE", "api")
+	t.Setenv("AUTH_CLOCK_SKEW", "1m")
+	t.Setenv("AUTH_JWT_PRIVATE_KEY_FILE", "/etc/jwt.pem")
+	t.Setenv("AUTH_JWT_KEY_ID", "2026-08")
+	t.Setenv("AUTH_ACCESS_TOKEN_TTL", "5m")
+	t.Setenv("AUTH_REFRESH_TOKEN_TTL", "24h")
+	t.Setenv("AUTH_TOKEN_PURGE_INTERVAL", "10m")
+	t.Setenv("AUTH_API_KEY_PREFIX", "svc")
+	t.Setenv("AUTH_ARGON2_MEMORY", "19456")
+	t.Setenv("AUTH_ARGON2_ITERATIONS", "2")
+	t.Setenv("AUTH_ARGON2_PARALLELISM", "1")
+	t.Setenv("AUTH_VERIFY_EMAIL_TTL", "24h")
+	t.Setenv("AUTH_PASSWORD_RESET_TTL", "30m")
+	t.Setenv("AUTH_MFA_ENCRYPTION_KEY", "bWZhLWtleQ==")
+	t.Setenv("AUTH_MFA_ISSUER", "Example")
+	t.Setenv("AUTH_MFA_CHALLENGE_TTL", "2m")
+	t.Setenv("AUTH_LOCKOUT_ACCOUNT_THRESHOLD", "3")
+	t.Setenv("AUTH_LOCKOUT_IP_THRESHOLD", "100")
+	t.Setenv("AUTH_LOCKOUT_DURATION", "10s")
+	t.Setenv("AUTH_LOCKOUT_MAX_DURATION", "15m")
+	t.Setenv("AUTH_LOCKOUT_RESET_AFTER", "1h")
+	t.Setenv("MAIL_DRIVER", "smtp")
+	t.Setenv("MAIL_FROM", "App <app@example.com>")
+	t.Setenv("MAIL_SMTP_HOST", "smtp.example.com")
+	t.Setenv("MAIL_SMTP_PORT", "2525")
+	t.Setenv("MAIL_SMTP_USERNAME", "mailer")
+	t.Setenv("MAIL_SMTP_PASSWORD", "smtp-secret")
+	t.Setenv("MAIL_LINK_BASE_URL", "https://app.example.com")
+	t.Setenv("MAIL_DEFAULT_LOCALE", "es")
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	t.Setenv("RATE_LIMIT_AUTH", "5/10s")
+	t.Setenv("RATE_LIMIT_API", "100/1h")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com,https://*.example.com")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET,POST")
+	t.Setenv("CORS_ALLOWED_HEADERS", "Authorization")
+	t.Setenv("CORS_EXPOSED_HEADERS", "X-Request-ID")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("IDEMPOTENCY_TTL", "1h")
+	t.Setenv("IDEMPOTENCY_LOCK_TIMEOUT", "30s")
+	t.Setenv("CONCURRENCY_LIMIT", "50")
+	t.Setenv("CONCURRENCY_ADAPTIVE", "true")
+	t.Setenv("CONCURRENCY_MIN_LIMIT", "5")
+	t.Setenv("CONCURRENCY_MAX_LIMIT", "200")
+	t.Setenv("CONCURRENCY_TARGET_LATENCY", "100ms")
+	t.Setenv("CONCURRENCY_QUEUE_SIZE", "20")
+	t.Setenv("CONCURRENCY_HIGH_QUEUE_SIZE", "3")
+	t.Setenv("CONCURRENCY_QUEUE_TIMEOUT", "50ms")
 
 	cfg, err := Load()
 	if err != nil {
@@ -99,10 +249,25 @@ func TestLoadFromEnv(t *testing.T) {
 
 	want := Config{
 		Server: ServerConfig{
-			Port:         9090,
-			Mode:         "debug",
-			ReadTimeout:  5 * time.Second,
-			WriteTimeout: time.Minute,
+			Port:           9090,
+			Mode:           "debug",
+			ReadTimeout:    5 * time.Second,
+			WriteTimeout:   time.Minute,
+			RequestTimeout: 45 * time.Second,
+			MaxBodyBytes:   64 << 10,
+			CompressMin:    256,
+			TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+			ErrorFormat:    "problem",
+			LegacyDetails:  true,
+			DefaultLocale:  "es",
+			StrictJSON:     true,
+			RouteTimeouts:  map[string]time.Duration{"/v1/auth": 5 * time.Second, "/v1/users/:userID/mfa": 3 * time.Second},
+
+			HSTSPreload:           true,
+			ContentSecurityPolicy: "script-src 'nonce-{nonce}'",
+			ReferrerPolicy:        "same-origin",
+			FrameOptions:          "SAMEORIGIN",
+			PermissionsPolicy:     "camera=()",
 		},
 		DB: DBConfig{
 			Host:            "db.internal",
@@ -116,8 +281,64 @@ func TestLoadFromEnv(t *testing.T) {
 			ConnMaxLifetime: 90 * time.Second,
 		},
 		Log: LogConfig{Level: "debug", Format: "text"},
+		OTEL: OTELConfig{
+			Endpoint:    "http://collector:4318",
+			ServiceName: "users-api",
+			SampleRatio: 0.25,
+		},
+		Auth: AuthConfig{
+			JWTAlgorithm:      "EdDSA",
+			JWKSFile:          "/etc/jwks.json",
+			JWTPrivateKeyFile: "/etc/jwt.pem",
+			JWTKeyID:          "2026-08",
+			JWTIssuer:         "https://issuer.example.com",
+			JWTAudience:       "api",
+			ClockSkew:         time.Minute,
+			AccessTokenTTL:    5 * time.Minute,
+			RefreshTokenTTL:   24 * time.Hour,
+			TokenPurgeEvery:   10 * time.Minute,
+			VerifyEmailTTL:    24 * time.Hour,
+			PasswordResetTTL:  30 * time.Minute,
+			MFAEncryptionKey:  "bWZhLWtleQ==",
+			MFAIssuer:         "Example",
+			MFAChallengeTTL:   2 * time.Minute,
+			AccountLockAfter:  3,
+			IPLockAfter:       100,
+			LockoutDuration:   10 * time.Second,
+			MaxLockout:        15 * time.Minute,
+			LockoutResetAfter: time.Hour,
+			APIKeyPrefix:      "svc",
+			Argon2Memory:      19456,
+			Argon2Iterations:  2,
+			Argon2Parallelism: 1,
+		},
+		Mail: MailConfig{
+			Driver:        "smtp",
+			From:          "App <app@example.com>",
+			FileDir:       "tmp/mail",
+			SMTPHost:      "smtp.example.com",
+			SMTPPort:      2525,
+			SMTPUsername:  "mailer",
+			SMTPPassword:  "smtp-secret",
+			LinkBaseURL:   "https://app.example.com",
+			DefaultLocale: "es",
+		},
+		RateLimit: RateLimitConfig{Enabled: false, Auth: "5/10s", API: "100/1h"},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Authorization"},
+			ExposedHeaders:   []string{"X-Request-ID"},
+			MaxAge:           time.Hour,
+			AllowCredentials: true,
+		},
+		Idempotency: IdempotencyConfig{TTL: time.Hour, LockTimeout: 30 * time.Second},
+		Concurrency: ConcurrencyConfig{
+			Limit: 50, Adaptive: true, MinLimit: 5, MaxLimit: 200, TargetLatency: 100 * time.Millisecond,
+			QueueSize: 20, HighQueueSize: 3, QueueTimeout: 50 * time.Millisecond,
+		},
 	}
-	if *cfg != want {
+	if !reflect.DeepEqual(*cfg, want) {
 		t.Errorf("Load() = %+v, want %+v", *cfg, want)
 	}
 }
@@ -140,6 +361,24 @@ func TestLoadUnsetsPassword(t *testing.T) {
 	}
 }
 
+// The JWT secret is cleared from the environment the same way.
+func TestLoadUnsetsJWTSecret(t *testing.T) {
+	isolate(t)
+	setRequired(t)
+	t.Setenv("AUTH_JWT_SECRET", "jwt-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Auth.JWTSecret != "jwt-secret" {
+		t.Errorf("cfg.Auth.JWTSecret = %q, want %q", cfg.Auth.JWTSecret, "jwt-secret")
+	}
+	if got, ok := os.LookupEnv("AUTH_JWT_SECRET"); ok {
+		t.Errorf("AUTH_JWT_SECRET still set to %q after Load()", got)
+	}
+}
+
 func TestLoadMissingRequired(t *testing.T) {
 	required := []string{"DB_HOST", "DB_USER", "DB_PASSWORD", "DB_NAME"}
 	for _, missing := range required {
@@ -3,29 +3,38 @@ package apperror
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/aarondever/go-gin-template/internal/validation"
 	"github.com/go-playground/validator/v10"
 )
 
 type Code string
 
 const (
-	CodeInvalidInput Code = "INVALID_INPUT"
-	CodeUnauthorized Code = "UNAUTHORIZED"
-	CodeForbidden    Code = "FORBIDDEN"
-	CodeNotFound     Code = "NOT_FOUND"
-	CodeConflict     Code = "CONFLICT"
-	CodeRateLimited  Code = "RATE_LIMITED"
-	CodeCanceled     Code = "CANCELED"
-	CodeTimeout      Code = "TIMEOUT"
-	CodeInternal     Code = "INTERNAL"
+	CodeInvalidInput         Code = "INVALID_INPUT"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeForbidden            Code = "FORBIDDEN"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeConflict             Code = "CONFLICT"
+	CodePayloadTooLarge      Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeRateLimited          Code = "RATE_LIMITED"
+	CodeCanceled             Code = "CANCELED"
+	CodeTimeout              Code = "TIMEOUT"
+	CodeUnavailable          Code = "UNAVAILABLE"
+	CodeInternal             Code = "INTERNAL"
 )
 
 type AppError struct {
-	Code    Code
-	Message string
-	Details map[string]string // optional, e.g. field-level validation
-	Err     error             // internal cause, never serialized
+	Code       Code
+	Message    string
+	Details    map[string]string // optional, e.g. the limit that was exceeded
+	Violations []Violation       // optional; one per rule a request broke
+	RetryAfter time.Duration     // optional; sent as the Retry-After header
+	Err        error             // internal cause, never serialized
 }
 
 func (e *AppError) Error() string {
@@ -50,6 +59,17 @@ func (e *AppError) WithDetails(d map[string]string) *AppError {
 	return e
 }
 
+func (e *AppError) WithViolations(v []Violation) *AppError {
+	e.Violations = v
+	return e
+}
+
+// WithRetryAfter tells the client how long to wait before trying again.
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	e.RetryAfter = d
+	return e
+}
+
 // From normalizes any error into an *AppError. Returns nil for nil.
 func From(err error) *AppError {
 	if err == nil {
@@ -63,12 +83,18 @@ func From(err error) *AppError {
 
 	var ve validator.ValidationErrors
 	if errors.As(err, &ve) {
-		details := make(map[string]string, len(ve))
-		for _, fe := range ve {
-			details[fe.Field()] = fe.Tag()
-		}
 		return Wrap(err, CodeInvalidInput, "validation failed").
-			WithDetails(details)
+			WithViolations(Violations(ve, validation.DefaultLocale))
+	}
+
+	if appErr := jsonError(err); appErr != nil {
+		return appErr
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return Wrap(err, CodePayloadTooLarge, "request body too large").
+			WithDetails(map[string]string{"limit": strconv.FormatInt(tooLarge.Limit, 10)})
 	}
 
 	switch {
@@ -2,9 +2,13 @@ package apperror
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -106,6 +110,17 @@ func TestWithDetails(t *testing.T) {
 	}
 }
 
+func TestWithRetryAfter(t *testing.T) {
+	err := New(CodeRateLimited, "slow down")
+
+	if got := err.WithRetryAfter(90 * time.Second); got != err {
+		t.Errorf("WithRetryAfter() returned a different error, want the receiver for chaining")
+	}
+	if err.RetryAfter != 90*time.Second {
+		t.Errorf("RetryAfter = %v, want 90s", err.RetryAfter)
+	}
+}
+
 func TestFromNil(t *testing.T) {
 	if got := From(nil); got != nil {
 		t.Errorf("From(nil) = %v, want nil", got)
@@ -148,14 +163,15 @@ func TestFromValidationErrors(t *testing.T) {
 	if got.Message != "validation failed" {
 		t.Errorf("Message = %q, want %q", got.Message, "validation failed")
 	}
-	want := map[string]string{"Email": "required", "Age": "gte"}
-	if len(got.Details) != len(want) {
-		t.Fatalf("Details = %v, want %v", got.Details, want)
+	want := []Violation{
+		{Path: "/Email", Rule: "required", Message: "is required"},
+		{Path: "/Age", Rule: "gte", Param: "18", Message: "must be at least 18"},
+	}
+	if !reflect.DeepEqual(got.Violations, want) {
+		t.Errorf("Violations = %+v, want %+v", got.Violations, want)
 	}
-	for field, tag := range want {
-		if got.Details[field] != tag {
-			t.Errorf("Details[%q] = %q, want %q", field, got.Details[field], tag)
-		}
+	if got.Details != nil {
+		t.Errorf("Details = %v, want none", got.Details)
 	}
 	// ValidationErrors is a slice, so it is unusable with errors.Is.
 	var unwrapped validator.ValidationErrors
@@ -179,8 +195,8 @@ func TestFromWrappedValidationErrors(t *testing.T) {
 	if got.Code != CodeInvalidInput {
 		t.Errorf("Code = %q, want %q", got.Code, CodeInvalidInput)
 	}
-	if got.Details["Name"] != "required" {
-		t.Errorf("Details = %v, want Name=required", got.Details)
+	if len(got.Violations) != 1 || got.Violations[0].Path != "/Name" || got.Violations[0].Rule != "required" {
+		t.Errorf("Violations = %+v, want /Name required", got.Violations)
 	}
 }
 
@@ -215,6 +231,12 @@ func TestFromStandardErrors(t *testing.T) {
 			code:    CodeTimeout,
 			message: "request timed out",
 		},
+		{
+			name:    "body too large",
+			err:     fmt.Errorf("bind: %w", &http.MaxBytesError{Limit: 1024}),
+			code:    CodePayloadTooLarge,
+			message: "request body too large",
+		},
 		{
 			name:    "unknown",
 			err:     errors.New("something broke"),
@@ -240,6 +262,66 @@ func TestFromStandardErrors(t *testing.T) {
 	}
 }
 
+func TestFromJSONErrors(t *testing.T) {
+	type address struct {
+		Zip string `json:"zip"`
+	}
+	var dst struct {
+		Name      string    `json:"name"`
+		Age       uint      `json:"age"`
+		Addresses []address `json:"addresses"`
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+		details map[string]string
+		want    []Violation
+	}{
+		{name: "syntax", body: `{"name" "Ada"}`, message: "malformed JSON", details: map[string]string{"offset": "9"}},
+		{name: "string", body: `{"name":5}`, message: "invalid request body", want: []Violation{
+			{Path: "/name", Rule: "type", Param: "string", Message: "must be a string"},
+		}},
+		{name: "unsigned", body: `{"age":-1}`, message: "invalid request body", want: []Violation{
+			{Path: "/age", Rule: "type", Param: "integer", Message: "must be a whole number of 0 or more"},
+		}},
+		{name: "nested", body: `{"addresses":[{"zip":1}]}`, message: "invalid request body", want: []Violation{
+			{Path: "/addresses/0/zip", Rule: "type", Param: "string", Message: "must be a string"},
+		}},
+		{name: "body", body: `[1]`, message: "invalid request body", want: []Violation{
+			{Path: "", Rule: "type", Param: "object", Message: "must be an object"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json.Unmarshal([]byte(tt.body), &dst)
+			got := From(fmt.Errorf("bind: %w", err))
+
+			if got.Code != CodeInvalidInput || got.Message != tt.message {
+				t.Errorf("From() = %s %q, want %s %q", got.Code, got.Message, CodeInvalidInput, tt.message)
+			}
+			if !reflect.DeepEqual(got.Details, tt.details) {
+				t.Errorf("Details = %v, want %v", got.Details, tt.details)
+			}
+			if !reflect.DeepEqual(got.Violations, tt.want) {
+				t.Errorf("Violations = %+v\nwant %+v", got.Violations, tt.want)
+			}
+			if !errors.Is(got, err) {
+				t.Errorf("From() dropped the original error %v", err)
+			}
+		})
+	}
+}
+
+func TestFromMaxBytesErrorDetails(t *testing.T) {
+	got := From(&http.MaxBytesError{Limit: 1024})
+
+	if got.Details["limit"] != "1024" {
+		t.Errorf("Details = %v, want limit 1024", got.Details)
+	}
+}
+
 // A canceled context reaches From as ctx.Err(), not as the sentinel itself.
 func TestFromContextErr(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
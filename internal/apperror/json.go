@@ -0,0 +1,70 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonError classifies a JSON body that does not decode, however it was
+// bound: malformed JSON, with the byte offset of the mistake, or a value of
+// the wrong type, with a violation naming the field and the type expected. It
+// returns nil for any other error.
+func jsonError(err error) *AppError {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return Wrap(err, CodeInvalidInput, "malformed JSON").
+			WithDetails(map[string]string{"offset": strconv.FormatInt(syntaxErr.Offset, 10)})
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		want, message := jsonType(typeErr.Type)
+		return Wrap(err, CodeInvalidInput, "invalid request body").WithViolations([]Violation{{
+			Path:    jsonPointer(typeErr.Field),
+			Rule:    "type",
+			Param:   want,
+			Message: message,
+		}})
+	}
+	return nil
+}
+
+// jsonType names the JSON type a Go type decodes from, with the message
+// telling a client to send it.
+func jsonType(t reflect.Type) (want, message string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", "must be a string"
+	case reflect.Bool:
+		return "boolean", "must be true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer", "must be a whole number"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", "must be a whole number of 0 or more"
+	case reflect.Float32, reflect.Float64:
+		return "number", "must be a number"
+	case reflect.Slice, reflect.Array:
+		return "array", "must be an array"
+	}
+	return "object", "must be an object"
+}
+
+// jsonPointer turns encoding/json's dotted field path, e.g. "addresses.0.zip",
+// into a JSON pointer; the empty path, the body itself, is "".
+func jsonPointer(field string) string {
+	if field == "" {
+		return ""
+	}
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		parts[i] = escape.Replace(p)
+	}
+	return "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,143 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// catalogs translate error messages, and violation messages not written by a
+// validator, keyed by the English message they are created with. English
+// needs no catalog; a message missing from one is sent in English.
+var catalogs = map[string]map[string]string{
+	"es": {
+		"Content-Type must be application/json":              "Content-Type debe ser application/json",
+		"Idempotency-Key was used with a different request":  "la Idempotency-Key ya se usó con otra petición",
+		"a request with this Idempotency-Key is in progress": "hay una petición en curso con esta Idempotency-Key",
+		"already exists":                         "ya existe",
+		"api key expired":                        "la clave de API ha caducado",
+		"api key not found":                      "clave de API no encontrada",
+		"authentication required":                "se requiere autenticación",
+		"conflict":                               "conflicto",
+		"conflicts with an existing record":      "entra en conflicto con un registro existente",
+		"cross-origin request not allowed":       "petición de origen cruzado no permitida",
+		"email already in use":                   "el correo electrónico ya está en uso",
+		"internal server error":                  "error interno del servidor",
+		"invalid Idempotency-Key header":         "cabecera Idempotency-Key no válida",
+		"invalid api key":                        "clave de API no válida",
+		"invalid code":                           "código no válido",
+		"invalid email or password":              "correo electrónico o contraseña incorrectos",
+		"invalid input":                          "entrada no válida",
+		"invalid or expired challenge":           "desafío no válido o caducado",
+		"invalid or expired link":                "enlace no válido o caducado",
+		"invalid parameters":                     "parámetros no válidos",
+		"invalid refresh token":                  "token de refresco no válido",
+		"invalid request body":                   "cuerpo de la petición no válido",
+		"invalid token":                          "token no válido",
+		"invalid value":                          "valor no válido",
+		"is already in use":                      "ya está en uso",
+		"is not a known field":                   "no es un campo conocido",
+		"is not allowed":                         "no está permitido",
+		"is out of range":                        "está fuera de rango",
+		"is required":                            "es obligatorio",
+		"malformed JSON":                         "JSON mal formado",
+		"mfa code required":                      "se requiere un código de autenticación multifactor",
+		"mfa already enabled":                    "la autenticación multifactor ya está activada",
+		"missing required value":                 "falta un valor obligatorio",
+		"missing token":                          "falta el token",
+		"must be a duration such as 1h30m":       "debe ser una duración como 1h30m",
+		"must be a number":                       "debe ser un número",
+		"must be a string":                       "debe ser una cadena de texto",
+		"must be a whole number":                 "debe ser un número entero",
+		"must be a whole number of 0 or more":    "debe ser un número entero igual o mayor que 0",
+		"must be an RFC 3339 timestamp":          "debe ser una marca de tiempo RFC 3339",
+		"must be an array":                       "debe ser una lista",
+		"must be an object":                      "debe ser un objeto",
+		"must be true or false":                  "debe ser true o false",
+		"no mfa enrollment in progress":          "no hay ninguna activación de autenticación multifactor en curso",
+		"not found":                              "no encontrado",
+		"overlaps an existing record":            "se solapa con un registro existente",
+		"permission denied":                      "permiso denegado",
+		"rate limit exceeded":                    "límite de peticiones superado",
+		"refers to a record that does not exist": "hace referencia a un registro que no existe",
+		"refresh token expired":                  "el token de refresco ha caducado",
+		"refresh token not found":                "token de refresco no encontrado",
+		"related record not found":               "registro relacionado no encontrado",
+		"request body is empty":                  "el cuerpo de la petición está vacío",
+		"request body too large":                 "el cuerpo de la petición es demasiado grande",
+		"request canceled":                       "petición cancelada",
+		"request timed out":                      "la petición ha excedido el tiempo de espera",
+		"server is overloaded":                   "el servidor está sobrecargado",
+		"service unavailable":                    "servicio no disponible",
+		"still referenced by other records":      "otros registros aún hacen referencia a él",
+		"timestamps must be RFC 3339":            "las marcas de tiempo deben ser RFC 3339",
+		"token expired":                          "el token ha caducado",
+		"token not found":                        "token no encontrado",
+		"too many failed attempts":               "demasiados intentos fallidos",
+		"user not found":                         "usuario no encontrado",
+		"validation failed":                      "la validación ha fallado",
+		"value too long":                         "valor demasiado largo",
+	},
+}
+
+// Localizer translates errors into the client's language, falling back to a
+// default locale for languages without a translation.
+type Localizer struct {
+	fallback string
+	matcher  language.Matcher
+	tags     []language.Tag
+}
+
+// NewLocalizer supports English and every locale with a catalog. fallback
+// must be one of them.
+func NewLocalizer(fallback string) (*Localizer, error) {
+	l := &Localizer{fallback: fallback, tags: []language.Tag{language.Make(validation.DefaultLocale)}}
+	for locale := range catalogs {
+		l.tags = append(l.tags, language.Make(locale))
+	}
+	i := slices.Index(l.tags, language.Make(fallback))
+	if i < 0 {
+		return nil, fmt.Errorf("apperror: no messages for default locale %q", fallback)
+	}
+	// The fallback goes first: the matcher returns it when nothing matches.
+	l.tags[0], l.tags[i] = l.tags[i], l.tags[0]
+	l.matcher = language.NewMatcher(l.tags)
+	return l, nil
+}
+
+// Match picks the best supported locale for an Accept-Language header value.
+func (l *Localizer) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return l.fallback
+	}
+	_, i, _ := l.matcher.Match(tags...)
+	return l.tags[i].String()
+}
+
+// Localize returns a copy of appErr with its message and violations in
+// locale's language; appErr itself, and so what is logged, stays in English.
+func (l *Localizer) Localize(appErr *AppError, locale string) *AppError {
+	out := *appErr
+	if msg, ok := catalogs[locale][appErr.Message]; ok {
+		out.Message = msg
+	}
+	var ve validator.ValidationErrors
+	switch {
+	case appErr.Violations == nil:
+	case errors.As(appErr.Err, &ve):
+		out.Violations = Violations(ve, locale)
+	default:
+		out.Violations = slices.Clone(appErr.Violations)
+		for i, v := range out.Violations {
+			if msg, ok := catalogs[locale][v.Message]; ok {
+				out.Violations[i].Message = msg
+			}
+		}
+	}
+	return &out
+}
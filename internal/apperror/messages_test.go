@@ -0,0 +1,92 @@
+package apperror
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestNewLocalizerUnknownFallback(t *testing.T) {
+	if _, err := NewLocalizer("xx"); err == nil {
+		t.Error("NewLocalizer(xx) error = nil, want one")
+	}
+}
+
+func TestLocalizerMatch(t *testing.T) {
+	l, err := NewLocalizer("en")
+	if err != nil {
+		t.Fatalf("NewLocalizer() error = %v", err)
+	}
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9", "es"},
+		{"fr-FR, es;q=0.5", "es"},
+		{"de", "en"},
+		{"en;q=0.4, es;q=0.8", "es"},
+		{";;;", "en"},
+	}
+	for _, tt := range tests {
+		if got := l.Match(tt.acceptLanguage); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+		}
+	}
+
+	l, err = NewLocalizer("es")
+	if err != nil {
+		t.Fatalf("NewLocalizer() error = %v", err)
+	}
+	if got := l.Match("de"); got != "es" {
+		t.Errorf("Match(de) with fallback es = %q, want es", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	l, err := NewLocalizer("en")
+	if err != nil {
+		t.Fatalf("NewLocalizer() error = %v", err)
+	}
+
+	type req struct {
+		Email string `json:"email" validate:"required"`
+	}
+	invalid := From(validation.ValidateStruct(req{}))
+	got := l.Localize(invalid, "es")
+	if got.Message != "la validación ha fallado" {
+		t.Errorf("Message = %q, want it translated", got.Message)
+	}
+	if len(got.Violations) != 1 || got.Violations[0].Message != "es obligatorio" {
+		t.Errorf("Violations = %+v, want them translated", got.Violations)
+	}
+	if invalid.Message != "validation failed" || invalid.Violations[0].Message != "is required" {
+		t.Errorf("original changed to %q, %+v; want it left in English", invalid.Message, invalid.Violations)
+	}
+	var ve validator.ValidationErrors
+	if !errors.As(got, &ve) || got.Code != invalid.Code {
+		t.Errorf("Localize() = %+v, want code and cause kept", got)
+	}
+
+	// Violations not from a validator translate through the catalog.
+	conflict := New(CodeConflict, "email already in use").
+		WithViolations([]Violation{{Path: "/email", Rule: "unique", Message: "is already in use"}})
+	if got := l.Localize(conflict, "es"); got.Violations[0].Message != "ya está en uso" {
+		t.Errorf("Violations = %+v, want them translated", got.Violations)
+	}
+	if conflict.Violations[0].Message != "is already in use" {
+		t.Errorf("original violations changed to %+v", conflict.Violations)
+	}
+
+	// Messages without a translation stay as they are.
+	untranslated := New(CodeConflict, "something new")
+	if got := l.Localize(untranslated, "es"); got.Message != "something new" {
+		t.Errorf("Message = %q, want it kept", got.Message)
+	}
+	if got := l.Localize(New(CodeNotFound, "user not found"), "en"); got.Message != "user not found" {
+		t.Errorf("Message = %q, want English kept", got.Message)
+	}
+}
@@ -0,0 +1,123 @@
+package apperror
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Definition says how failures with a code are reported.
+type Definition struct {
+	Code      Code
+	Status    int        // HTTP status of the response
+	Message   string     // sent when the error has no message of its own
+	LogLevel  slog.Level // level the failure is logged at
+	Retryable bool       // whether the same request may succeed later
+	Doc       string     // when the code is used, for the API reference
+}
+
+var (
+	registryMu  sync.RWMutex
+	registry    = make(map[Code]Definition)
+	definitions []Definition // in registration order
+)
+
+func init() {
+	for _, d := range []Definition{
+		{
+			Code: CodeInvalidInput, Status: http.StatusBadRequest, Message: "invalid input", LogLevel: slog.LevelWarn,
+			Doc: "Malformed JSON, or a body, path or query that failed binding or validation, or a database constraint; `violations` lists the rules broken",
+		},
+		{
+			Code: CodeUnauthorized, Status: http.StatusUnauthorized, Message: "authentication required", LogLevel: slog.LevelWarn,
+			Doc: "Missing, malformed, expired or otherwise rejected credentials",
+		},
+		{
+			Code: CodeForbidden, Status: http.StatusForbidden, Message: "permission denied", LogLevel: slog.LevelWarn,
+			Doc: "Authenticated, but lacking the permission; `details.permission` names it",
+		},
+		{
+			Code: CodeNotFound, Status: http.StatusNotFound, Message: "not found", LogLevel: slog.LevelWarn,
+			Doc: "No row for the given id",
+		},
+		{
+			Code: CodeConflict, Status: http.StatusConflict, Message: "conflict", LogLevel: slog.LevelWarn,
+			Doc: "Unique or exclusion constraint violated (e.g. duplicate email), or the row is still referenced; `violations` names the field when known",
+		},
+		{
+			Code: CodePayloadTooLarge, Status: http.StatusRequestEntityTooLarge, Message: "request body too large", LogLevel: slog.LevelWarn,
+			Doc: "Body over the limit; `details.limit` gives it in bytes",
+		},
+		{
+			Code: CodeUnsupportedMediaType, Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json", LogLevel: slog.LevelWarn,
+			Doc: "A `/v1` body not sent as `application/json`",
+		},
+		{
+			Code: CodeRateLimited, Status: http.StatusTooManyRequests, Message: "rate limit exceeded", LogLevel: slog.LevelWarn, Retryable: true,
+			Doc: "Too many requests or failed logins; `Retry-After` gives the seconds to wait",
+		},
+		{
+			// nginx's Client Closed Request.
+			Code: CodeCanceled, Status: 499, Message: "request canceled", LogLevel: slog.LevelWarn,
+			Doc: "Client disconnected; no body is written",
+		},
+		{
+			Code: CodeTimeout, Status: http.StatusGatewayTimeout, Message: "request timed out", LogLevel: slog.LevelError, Retryable: true,
+			Doc: "The request took longer than `SERVER_REQUEST_TIMEOUT`, or its route's `SERVER_ROUTE_TIMEOUTS` entry",
+		},
+		{
+			Code: CodeUnavailable, Status: http.StatusServiceUnavailable, Message: "service unavailable", LogLevel: slog.LevelError, Retryable: true,
+			Doc: "The server is at capacity and shed the request; `Retry-After` gives the seconds to wait",
+		},
+		{
+			Code: CodeInternal, Status: http.StatusInternalServerError, Message: "internal server error", LogLevel: slog.LevelError,
+			Doc: "Anything unclassified, including recovered panics",
+		},
+	} {
+		Register(d)
+	}
+}
+
+// Register declares a code, typically in a package-level var:
+//
+//	var CodeQuotaExceeded = apperror.Register(apperror.Definition{Code: "QUOTA_EXCEEDED", …})
+//
+// It panics if the code is empty or already registered, or the status is not
+// an error status: all are programming errors, caught at startup.
+func Register(d Definition) Code {
+	if d.Code == "" {
+		panic("apperror: Register with an empty code")
+	}
+	if d.Status < 400 || d.Status > 599 {
+		panic(fmt.Sprintf("apperror: code %s has status %d, want 4xx or 5xx", d.Code, d.Status))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[d.Code]; dup {
+		panic(fmt.Sprintf("apperror: code %s registered twice", d.Code))
+	}
+	registry[d.Code] = d
+	definitions = append(definitions, d)
+	return d.Code
+}
+
+// Lookup returns the definition of code. An unregistered code reports as
+// [CodeInternal] does, and ok is false.
+func Lookup(code Code) (d Definition, ok bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if d, ok = registry[code]; ok {
+		return d, true
+	}
+	return registry[CodeInternal], false
+}
+
+// Definitions lists every registered code, in the order registered.
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Clone(definitions)
+}
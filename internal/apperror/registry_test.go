@@ -0,0 +1,100 @@
+package apperror
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Every Code constant in this package must be registered, or errors with it
+// would be reported as INTERNAL.
+func TestEveryCodeRegistered(t *testing.T) {
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("list package files: %v", err)
+	}
+	fset := token.NewFileSet()
+	found := 0
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if typ, ok := vs.Type.(*ast.Ident); !ok || typ.Name != "Code" {
+					continue
+				}
+				for _, v := range vs.Values {
+					lit := v.(*ast.BasicLit)
+					code := Code(lit.Value[1 : len(lit.Value)-1])
+					found++
+					if _, ok := Lookup(code); !ok {
+						t.Errorf("code %s is not registered", code)
+					}
+				}
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("found no Code constants; has the declaration changed shape?")
+	}
+}
+
+func TestLookupUnregistered(t *testing.T) {
+	d, ok := Lookup("NO_SUCH_CODE")
+	if ok {
+		t.Error("Lookup(NO_SUCH_CODE) ok = true, want false")
+	}
+	if d.Code != CodeInternal || d.Status != http.StatusInternalServerError {
+		t.Errorf("Lookup(NO_SUCH_CODE) = %+v, want INTERNAL's definition", d)
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		def  Definition
+	}{
+		{name: "empty code", def: Definition{Status: http.StatusBadRequest}},
+		{name: "duplicate", def: Definition{Code: CodeNotFound, Status: http.StatusNotFound}},
+		{name: "success status", def: Definition{Code: "FINE", Status: http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%+v) did not panic", tt.def)
+				}
+			}()
+			Register(tt.def)
+		})
+	}
+}
+
+// Default messages are client-facing, so each needs its translations.
+func TestDefaultMessagesTranslated(t *testing.T) {
+	for _, d := range Definitions() {
+		if d.Message == "" || d.Doc == "" || d.LogLevel < slog.LevelInfo {
+			t.Errorf("%s: message, doc or log level missing: %+v", d.Code, d)
+		}
+		for locale, catalog := range catalogs {
+			if _, ok := catalog[d.Message]; !ok {
+				t.Errorf("%s: default message %q has no %s translation", d.Code, d.Message, locale)
+			}
+		}
+	}
+}
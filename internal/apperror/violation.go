@@ -0,0 +1,62 @@
+package apperror
+
+import (
+	"strings"
+
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// Violation is one rule a request broke.
+type Violation struct {
+	Path    string `json:"path"`            // JSON pointer to the field, e.g. "/addresses/0/zip"
+	Rule    string `json:"rule"`            // validator tag, e.g. "max"
+	Param   string `json:"param,omitempty"` // the rule's parameter, e.g. "100"
+	Message string `json:"message"`         // e.g. "must be at most 100 characters"
+}
+
+// Field is the last element of the path: the field's own name, without the
+// fields or indexes containing it.
+func (v Violation) Field() string {
+	field := v.Path[strings.LastIndexByte(v.Path, '/')+1:]
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(field)
+}
+
+// Violations describes each failed rule in ve, with messages in locale's
+// language.
+func Violations(ve validator.ValidationErrors, locale string) []Violation {
+	out := make([]Violation, len(ve))
+	for i, fe := range ve {
+		out[i] = Violation{
+			Path:    pointer(fe.Namespace()),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: validation.Message(fe, locale),
+		}
+	}
+	return out
+}
+
+// pointer turns a validator namespace such as "req.addresses[0].zip" into the
+// JSON pointer "/addresses/0/zip". The first element names the validated
+// struct, which is the document itself.
+func pointer(namespace string) string {
+	_, rest, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return ""
+	}
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for part := range strings.SplitSeq(rest, ".") {
+		// "tags[0]" or "labels[key]", possibly more than once: "grid[1][2]".
+		name, index, _ := strings.Cut(part, "[")
+		b.WriteString("/" + escape.Replace(name))
+		for index != "" {
+			var key string
+			key, index, _ = strings.Cut(index, "]")
+			b.WriteString("/" + escape.Replace(key))
+			index = strings.TrimPrefix(index, "[")
+		}
+	}
+	return b.String()
+}
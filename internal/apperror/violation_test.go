@@ -0,0 +1,85 @@
+package apperror
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestViolations(t *testing.T) {
+	type address struct {
+		Zip string `json:"zip" validate:"required,len=5"`
+	}
+	type request struct {
+		Name      string            `json:"name" validate:"required,max=10"`
+		Age       int               `json:"age" validate:"gte=18"`
+		Items     []int             `json:"items" validate:"max=2"`
+		Tags      []string          `json:"tags" validate:"dive,required"`
+		Addresses []address         `json:"addresses" validate:"dive"`
+		Labels    map[string]string `json:"labels" validate:"dive,keys,required,endkeys,required"`
+		Role      string            `json:"role" validate:"oneof=admin member"`
+		Password  string            `json:"password" validate:"password"`
+		Slash     string            `json:"a/b~c" validate:"email"`
+		Expires   time.Time         `json:"expires" validate:"gt"`
+		Code      string            `json:"code" validate:"uuid"`
+	}
+
+	err := validation.ValidateStruct(request{
+		Name:      "far too long a name",
+		Age:       17,
+		Items:     []int{1, 2, 3},
+		Tags:      []string{"a", ""},
+		Addresses: []address{{Zip: "12345"}, {Zip: "123"}},
+		Labels:    map[string]string{"env": ""},
+		Role:      "owner",
+		Password:  "weak",
+		Slash:     "nope",
+		Expires:   time.Now().Add(-time.Hour),
+		Code:      "x",
+	})
+	ve, ok := err.(validator.ValidationErrors)
+	if !ok {
+		t.Fatalf("ValidateStruct() error = %v, want validator.ValidationErrors", err)
+	}
+
+	want := []Violation{
+		{Path: "/name", Rule: "max", Param: "10", Message: "must be at most 10 characters"},
+		{Path: "/age", Rule: "gte", Param: "18", Message: "must be at least 18"},
+		{Path: "/items", Rule: "max", Param: "2", Message: "must be at most 2 items"},
+		{Path: "/tags/1", Rule: "required", Message: "is required"},
+		{Path: "/addresses/1/zip", Rule: "len", Param: "5", Message: "must be exactly 5 characters"},
+		{Path: "/labels/env", Rule: "required", Message: "is required"},
+		{Path: "/role", Rule: "oneof", Param: "admin member", Message: "must be one of admin, member"},
+		{
+			Path: "/password", Rule: "password",
+			Message: "must be 12 to 128 characters mixing at least two of lower case, upper case, digits and symbols",
+		},
+		{Path: "/a~1b~0c", Rule: "email", Message: "must be a valid email address"},
+		{Path: "/expires", Rule: "gt", Message: "must be in the future"},
+		{Path: "/code", Rule: "uuid", Message: "must satisfy uuid"},
+	}
+	got := Violations(ve, "en")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestViolationField(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/name", want: "name"},
+		{path: "/addresses/0/zip", want: "zip"},
+		{path: "/a~1b~0c", want: "a/b~c"},
+		{path: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := (Violation{Path: tt.path}).Field(); got != tt.want {
+			t.Errorf("Violation{Path: %q}.Field() = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
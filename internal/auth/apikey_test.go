@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, lookup, err := GenerateAPIKey("gk")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(key, lookup+"_") {
+		t.Errorf("key %q does not start with its lookup %q", key, lookup)
+	}
+	if !strings.HasPrefix(lookup, "gk_") || len(lookup) != len("gk_")+lookupLen {
+		t.Errorf("lookup = %q, want gk_ plus %d characters", lookup, lookupLen)
+	}
+	if got, ok := APIKeyLookup(key); !ok || got != lookup {
+		t.Errorf("APIKeyLookup(key) = %q, %v, want %q", got, ok, lookup)
+	}
+
+	other, _, _ := GenerateAPIKey("gk")
+	if other == key {
+		t.Error("two generated keys are identical")
+	}
+}
+
+func TestGenerateAPIKeyRejectsBadPrefix(t *testing.T) {
+	for _, prefix := range []string{"", "g_k"} {
+		if _, _, err := GenerateAPIKey(prefix); err == nil {
+			t.Errorf("GenerateAPIKey(%q) error = nil, want an error", prefix)
+		}
+	}
+}
+
+// The secret is base64url, which can itself contain "_"; the lookup is
+// everything up to the second underscore, not the last.
+func TestAPIKeyLookup(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{key: "gk_abcd1234_secret", want: "gk_abcd1234", wantOK: true},
+		{key: "gk_abcd1234_sec_ret", want: "gk_abcd1234", wantOK: true},
+		{key: "gk_abcd1234_", wantOK: false},
+		{key: "gk_abcd1234", wantOK: false},
+		{key: "_abcd1234_secret", wantOK: false},
+		{key: "nounderscores", wantOK: false},
+		{key: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := APIKeyLookup(tt.key)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("APIKeyLookup(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	a := HashAPIKey("gk_abcd1234_secret")
+
+	if a != HashAPIKey("gk_abcd1234_secret") {
+		t.Error("HashAPIKey is not deterministic")
+	}
+	if a == HashAPIKey("gk_abcd1234_secreT") {
+		t.Error("different keys hash the same")
+	}
+	if strings.Contains(a, "secret") {
+		t.Errorf("hash %q contains the key", a)
+	}
+}
+
+type resolverFunc func(ctx context.Context, key string) (*Principal, error)
+
+func (f resolverFunc) ResolveAPIKey(ctx context.Context, key string) (*Principal, error) {
+	return f(ctx, key)
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	var gotKey string
+	a := NewAPIKeyAuthenticator(resolverFunc(func(_ context.Context, key string) (*Principal, error) {
+		gotKey = key
+		return &Principal{Kind: KindAPIKey, Subject: "7"}, nil
+	}))
+
+	t.Run("header present", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(APIKeyHeader, "  gk_abcd1234_secret ")
+
+		p, err := a.Authenticate(req)
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if p.Kind != KindAPIKey || p.Subject != "7" {
+			t.Errorf("principal = %+v, want api key 7", p)
+		}
+		if gotKey != "gk_abcd1234_secret" {
+			t.Errorf("resolved key = %q, want it trimmed", gotKey)
+		}
+	})
+
+	t.Run("header absent", func(t *testing.T) {
+		_, err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+		if !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("Authenticate() error = %v, want ErrNoCredentials", err)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	"github.com/aarondever/go-gin-template/internal/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenIssuer signs access tokens that [JWTVerifier] accepts under the same
+// configuration.
+type TokenIssuer struct {
+	method   jwt.SigningMethod
+	key      any
+	kid      string
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewTokenIssuer builds an issuer from cfg. HS256 signs with the shared
+// secret; RS256 and EdDSA need AUTH_JWT_PRIVATE_KEY_FILE.
+func NewTokenIssuer(cfg config.AuthConfig) (*TokenIssuer, error) {
+	if cfg.AccessTokenTTL <= 0 {
+		return nil, fmt.Errorf("auth: AUTH_ACCESS_TOKEN_TTL must be positive")
+	}
+	i := &TokenIssuer{
+		kid:      cfg.JWTKeyID,
+		issuer:   cfg.JWTIssuer,
+		audience: cfg.JWTAudience,
+		ttl:      cfg.AccessTokenTTL,
+		now:      time.Now,
+	}
+
+	switch cfg.JWTAlgorithm {
+	case "HS256":
+		if len(cfg.JWTSecret) < minSecretLen {
+			return nil, fmt.Errorf("auth: AUTH_JWT_SECRET must be at least %d bytes", minSecretLen)
+		}
+		i.method, i.key = jwt.SigningMethodHS256, []byte(cfg.JWTSecret)
+
+	case "RS256", "EdDSA":
+		if cfg.JWTPrivateKeyFile == "" {
+			return nil, fmt.Errorf("auth: issuing %s tokens needs AUTH_JWT_PRIVATE_KEY_FILE", cfg.JWTAlgorithm)
+		}
+		key, err := loadPrivateKeyFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		if err := checkSigningKey(cfg.JWTAlgorithm, key); err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		i.key = key
+		i.method = jwt.SigningMethodRS256
+		if cfg.JWTAlgorithm == "EdDSA" {
+			i.method = jwt.SigningMethodEdDSA
+		}
+
+	default:
+		return nil, fmt.Errorf("auth: unsupported AUTH_JWT_ALGORITHM %q", cfg.JWTAlgorithm)
+	}
+	return i, nil
+}
+
+func checkSigningKey(alg string, key crypto.Signer) error {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		if alg == "RS256" {
+			return nil
+		}
+	case ed25519.PrivateKey:
+		if alg == "EdDSA" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%T cannot sign %s tokens", key, alg)
+}
+
+// Issue signs an access token for subject and returns it with its expiry.
+func (i *TokenIssuer) Issue(subject string) (token string, expiresAt time.Time, err error) {
+	now := i.now()
+	expiresAt = now.Add(i.ttl)
+
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        util.NewID(),
+		Subject:   subject,
+		Issuer:    i.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	t := jwt.NewWithClaims(i.method, claims)
+	if i.kid != "" {
+		t.Header["kid"] = i.kid
+	}
+	token, err = t.SignedString(i.key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
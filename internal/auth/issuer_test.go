@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+)
+
+func newIssuer(t *testing.T, cfg config.AuthConfig) *TokenIssuer {
+	t.Helper()
+	i, err := NewTokenIssuer(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer() error = %v", err)
+	}
+	i.now = func() time.Time { return fixedNow }
+	return i
+}
+
+func writePEM(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// What the issuer signs, the verifier under the same config accepts.
+func TestIssueHS256RoundTrip(t *testing.T) {
+	cfg := hsConfig()
+	cfg.AccessTokenTTL = 15 * time.Minute
+
+	token, expiresAt, err := newIssuer(t, cfg).Issue("42")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if want := fixedNow.Add(15 * time.Minute); !expiresAt.Equal(want) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, want)
+	}
+
+	p, err := newVerifier(t, cfg).Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if p.Subject != "42" || len(p.Scopes) != 0 {
+		t.Errorf("principal = %+v, want unscoped user 42", p)
+	}
+
+	expired := newVerifier(t, cfg)
+	expired.now = func() time.Time { return fixedNow.Add(time.Hour) }
+	_, err = expired.Verify(token)
+	assertUnauthorized(t, err, "token expired")
+}
+
+func TestIssueEdDSARoundTrip(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+
+	cfg := config.AuthConfig{
+		JWTAlgorithm:      "EdDSA",
+		JWTPrivateKeyFile: writePEM(t, "jwt.pem", "PRIVATE KEY", privDER),
+		JWTPublicKeyFile:  writePEM(t, "jwt.pub", "PUBLIC KEY", pubDER),
+		JWTKeyID:          "2026-08",
+		AccessTokenTTL:    time.Minute,
+	}
+
+	token, _, err := newIssuer(t, cfg).Issue("7")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := newVerifier(t, cfg).Verify(token); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestNewTokenIssuerConfigErrors(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	edKey := writePEM(t, "ed.pem", "PRIVATE KEY", edDER)
+
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{name: "short secret", cfg: config.AuthConfig{JWTAlgorithm: "HS256", JWTSecret: "short", AccessTokenTTL: time.Minute}},
+		{name: "no ttl", cfg: config.AuthConfig{JWTAlgorithm: "HS256", JWTSecret: testSecret}},
+		{name: "RS256 without private key", cfg: config.AuthConfig{JWTAlgorithm: "RS256", AccessTokenTTL: time.Minute}},
+		{
+			name: "key of the wrong type",
+			cfg:  config.AuthConfig{JWTAlgorithm: "RS256", JWTPrivateKeyFile: edKey, AccessTokenTTL: time.Minute},
+		},
+		{name: "unknown algorithm", cfg: config.AuthConfig{JWTAlgorithm: "none", AccessTokenTTL: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTokenIssuer(tt.cfg); err == nil {
+				t.Error("NewTokenIssuer() error = nil, want a config error")
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// fixedNow is the verifier's clock in every test, so expiry is deterministic.
+var fixedNow = time.Date(2026, 8, 18, 10, 0, 0, 0, time.UTC)
+
+func hsConfig() config.AuthConfig {
+	return config.AuthConfig{
+		JWTAlgorithm: "HS256",
+		JWTSecret:    testSecret,
+		JWTIssuer:    "https://issuer.example.com",
+		JWTAudience:  "api",
+		ClockSkew:    30 * time.Second,
+	}
+}
+
+func newVerifier(t *testing.T, cfg config.AuthConfig) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	v.now = func() time.Time { return fixedNow }
+	return v
+}
+
+// validClaims are accepted by hsConfig; tests mutate a copy to break one thing.
+func validClaims() Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    "https://issuer.example.com",
+			Audience:  jwt.ClaimStrings{"api"},
+			IssuedAt:  jwt.NewNumericDate(fixedNow.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(fixedNow.Add(time.Hour)),
+		},
+		Scope: "users:read users:write",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims Claims, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return s
+}
+
+func assertUnauthorized(t *testing.T, err error, wantMsg string) {
+	t.Helper()
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, want an *AppError", err)
+	}
+	if appErr.Code != e.CodeUnauthorized {
+		t.Errorf("code = %q, want %q", appErr.Code, e.CodeUnauthorized)
+	}
+	if appErr.Message != wantMsg {
+		t.Errorf("message = %q, want %q", appErr.Message, wantMsg)
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v := newVerifier(t, hsConfig())
+
+	p, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims(), ""))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if p.Kind != KindUser || p.Subject != "42" {
+		t.Errorf("principal = %+v, want user 42", p)
+	}
+	if len(p.Scopes) != 2 || p.Scopes[0] != "users:read" || p.Scopes[1] != "users:write" {
+		t.Errorf("scopes = %v, want [users:read users:write]", p.Scopes)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Claims)
+		key     []byte
+		wantMsg string
+	}{
+		{
+			name:    "expired beyond the skew",
+			mutate:  func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(fixedNow.Add(-time.Minute)) },
+			wantMsg: "token expired",
+		},
+		{
+			name:    "missing expiry",
+			mutate:  func(c *Claims) { c.ExpiresAt = nil },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "wrong issuer",
+			mutate:  func(c *Claims) { c.Issuer = "https://evil.example.com" },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "wrong audience",
+			mutate:  func(c *Claims) { c.Audience = jwt.ClaimStrings{"other"} },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "not yet valid beyond the skew",
+			mutate:  func(c *Claims) { c.NotBefore = jwt.NewNumericDate(fixedNow.Add(time.Minute)) },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "issued in the future",
+			mutate:  func(c *Claims) { c.IssuedAt = jwt.NewNumericDate(fixedNow.Add(time.Hour)) },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "no subject",
+			mutate:  func(c *Claims) { c.Subject = "" },
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "signed with another secret",
+			key:     []byte("another-secret-another-secret-xx"),
+			wantMsg: "invalid token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVerifier(t, hsConfig())
+			claims := validClaims()
+			if tt.mutate != nil {
+				tt.mutate(&claims)
+			}
+			key := tt.key
+			if key == nil {
+				key = []byte(testSecret)
+			}
+
+			_, err := v.Verify(sign(t, jwt.SigningMethodHS256, key, claims, ""))
+
+			assertUnauthorized(t, err, tt.wantMsg)
+		})
+	}
+}
+
+// Expiry and not-before are allowed to be off by up to the configured skew.
+func TestVerifyClockSkew(t *testing.T) {
+	v := newVerifier(t, hsConfig())
+	claims := validClaims()
+	claims.ExpiresAt = jwt.NewNumericDate(fixedNow.Add(-10 * time.Second))
+	claims.NotBefore = jwt.NewNumericDate(fixedNow.Add(10 * time.Second))
+
+	if _, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims, "")); err != nil {
+		t.Errorf("Verify() error = %v, want the skew to absorb 10s", err)
+	}
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	v := newVerifier(t, hsConfig())
+
+	t.Run("none", func(t *testing.T) {
+		token := sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(), "")
+		_, err := v.Verify(token)
+		assertUnauthorized(t, err, "invalid token")
+	})
+
+	t.Run("HS512", func(t *testing.T) {
+		token := sign(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims(), "")
+		_, err := v.Verify(token)
+		assertUnauthorized(t, err, "invalid token")
+	})
+}
+
+func TestVerifyRS256PublicKeyFile(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	v := newVerifier(t, config.AuthConfig{JWTAlgorithm: "RS256", JWTPublicKeyFile: path})
+
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, priv, validClaims(), "")); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// The classic confusion attack: HS256 keyed with the public key bytes.
+	forged := sign(t, jwt.SigningMethodHS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), validClaims(), "")
+	_, err = v.Verify(forged)
+	assertUnauthorized(t, err, "invalid token")
+}
+
+func TestVerifyEdDSAJWKS(t *testing.T) {
+	pubA, privA, _ := ed25519.GenerateKey(rand.Reader)
+	pubB, privB, _ := ed25519.GenerateKey(rand.Reader)
+	_, privC, _ := ed25519.GenerateKey(rand.Reader)
+
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "OKP", "crv": "Ed25519", "kid": "a", "use": "sig", "x": base64.RawURLEncoding.EncodeToString(pubA)},
+		{"kty": "OKP", "crv": "Ed25519", "kid": "b", "x": base64.RawURLEncoding.EncodeToString(pubB)},
+		// Encryption keys are not verification keys.
+		{"kty": "OKP", "crv": "Ed25519", "kid": "enc", "use": "enc", "x": base64.RawURLEncoding.EncodeToString(pubB)},
+	}}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+
+	v := newVerifier(t, config.AuthConfig{JWTAlgorithm: "EdDSA", JWKSFile: path})
+
+	for kid, key := range map[string]ed25519.PrivateKey{"a": privA, "b": privB} {
+		if _, err := v.Verify(sign(t, jwt.SigningMethodEdDSA, key, validClaims(), kid)); err != nil {
+			t.Errorf("kid %s: Verify() error = %v", kid, err)
+		}
+	}
+
+	tests := map[string]string{
+		"unknown kid":        sign(t, jwt.SigningMethodEdDSA, privA, validClaims(), "zzz"),
+		"missing kid":        sign(t, jwt.SigningMethodEdDSA, privA, validClaims(), ""),
+		"kid of another key": sign(t, jwt.SigningMethodEdDSA, privC, validClaims(), "a"),
+		"encryption key":     sign(t, jwt.SigningMethodEdDSA, privB, validClaims(), "enc"),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.Verify(token)
+			assertUnauthorized(t, err, "invalid token")
+		})
+	}
+}
+
+func TestNewJWTVerifierConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{name: "short secret", cfg: config.AuthConfig{JWTAlgorithm: "HS256", JWTSecret: "short"}},
+		{name: "no secret", cfg: config.AuthConfig{JWTAlgorithm: "HS256"}},
+		{name: "RS256 without keys", cfg: config.AuthConfig{JWTAlgorithm: "RS256"}},
+		{name: "missing key file", cfg: config.AuthConfig{JWTAlgorithm: "EdDSA", JWTPublicKeyFile: "/nonexistent.pem"}},
+		{name: "unknown algorithm", cfg: config.AuthConfig{JWTAlgorithm: "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewJWTVerifier(tt.cfg); err == nil {
+				t.Error("NewJWTVerifier() error = nil, want a config error")
+			}
+		})
+	}
+}
+
+func TestAuthenticateBearerHeader(t *testing.T) {
+	v := newVerifier(t, hsConfig())
+	token := sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims(), "")
+
+	tests := []struct {
+		name     string
+		header   string
+		wantNone bool
+	}{
+		{name: "bearer", header: "Bearer " + token},
+		{name: "scheme is case-insensitive", header: "bearer " + token},
+		{name: "no header", header: "", wantNone: true},
+		{name: "other scheme", header: "Basic dXNlcjpwYXNz", wantNone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			p, err := v.Authenticate(req)
+
+			if tt.wantNone {
+				if !errors.Is(err, ErrNoCredentials) {
+					t.Errorf("Authenticate() error = %v, want ErrNoCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if p.Subject != "42" {
+				t.Errorf("subject = %q, want %q", p.Subject, "42")
+			}
+		})
+	}
+}
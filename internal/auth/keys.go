@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// keySet holds the verification keys for one algorithm. A lone key matches any
+// kid; with several, the token's kid picks one.
+type keySet struct {
+	byKID map[string]crypto.PublicKey
+	only  crypto.PublicKey
+}
+
+func (s *keySet) lookup(kid string) (crypto.PublicKey, error) {
+	if s.only != nil {
+		return s.only, nil
+	}
+	if kid == "" {
+		return nil, errors.New("token has no kid and the key set holds several keys")
+	}
+	key, ok := s.byKID[kid]
+	if !ok {
+		return nil, fmt.Errorf("no key with kid %q", kid)
+	}
+	return key, nil
+}
+
+func singleKey(key crypto.PublicKey) *keySet {
+	return &keySet{only: key}
+}
+
+// loadPublicKeyFile reads a PEM public key (PKIX or PKCS#1) or certificate.
+func loadPublicKeyFile(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s: no PEM block", path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("public key %s: unsupported PEM type %q", path, block.Type)
+}
+
+// jwk is the subset of RFC 7517 needed for RSA and Ed25519 signature keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+}
+
+// loadJWKSFile reads a local JWKS and keeps the signature keys usable with alg.
+func loadJWKSFile(path, alg string) (*keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	set := &keySet{byKID: make(map[string]crypto.PublicKey)}
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Alg != "" && k.Alg != alg {
+			continue
+		}
+		key, err := k.publicKey(alg)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		set.byKID[k.Kid] = key
+	}
+
+	switch len(set.byKID) {
+	case 0:
+		return nil, fmt.Errorf("jwks %s: no %s signature keys", path, alg)
+	case 1:
+		for _, key := range set.byKID {
+			set.only = key
+		}
+	}
+	return set, nil
+}
+
+// publicKey decodes k, or returns nil if k is not a key type alg can use.
+func (k jwk) publicKey(alg string) (crypto.PublicKey, error) {
+	switch {
+	case alg == "RS256" && k.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent out of range")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+
+	case alg == "EdDSA" && k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ed25519 key is %d bytes, want %d", len(x), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+// loadPrivateKeyFile reads a PEM private key (PKCS#8 or PKCS#1) for signing.
+func loadPrivateKeyFile(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key %s: no PEM block", path)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("private key %s: %T cannot sign", path, key)
+		}
+		return signer, nil
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("private key %s: unsupported PEM type %q", path, block.Type)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aarondever/go-gin-template/config"
+)
+
+// cheapParams keep the tests fast; production defaults are far costlier.
+var cheapParams = config.AuthConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}
+
+func newHasher(t *testing.T, cfg config.AuthConfig) *PasswordHasher {
+	t.Helper()
+	h, err := NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher() error = %v", err)
+	}
+	return h
+}
+
+func TestPasswordHashVerify(t *testing.T) {
+	h := newHasher(t, cheapParams)
+
+	encoded, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash() = %q, want a PHC argon2id string with the configured params", encoded)
+	}
+
+	match, rehash, err := h.Verify("correct horse battery", encoded)
+	if err != nil || !match || rehash {
+		t.Errorf("Verify(right) = %v, %v, %v, want true, false, nil", match, rehash, err)
+	}
+
+	match, _, err = h.Verify("correct horse battery!", encoded)
+	if err != nil || match {
+		t.Errorf("Verify(wrong) = %v, %v, want false, nil", match, err)
+	}
+
+	again, _ := h.Hash("correct horse battery")
+	if again == encoded {
+		t.Error("two hashes of the same password are identical; salt not random")
+	}
+}
+
+// A hash made under old parameters still verifies, and asks to be upgraded.
+func TestPasswordVerifyRequestsRehash(t *testing.T) {
+	old := newHasher(t, cheapParams)
+	encoded, _ := old.Hash("correct horse battery")
+
+	stronger := cheapParams
+	stronger.Argon2Iterations = 2
+	h := newHasher(t, stronger)
+
+	match, rehash, err := h.Verify("correct horse battery", encoded)
+	if err != nil || !match || !rehash {
+		t.Errorf("Verify() = %v, %v, %v, want true, true, nil", match, rehash, err)
+	}
+
+	// A wrong password never asks for a rehash.
+	if _, rehash, _ := h.Verify("wrong", encoded); rehash {
+		t.Error("Verify(wrong) requested a rehash")
+	}
+}
+
+func TestPasswordVerifyMalformed(t *testing.T) {
+	h := newHasher(t, cheapParams)
+
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$2a$10$abcdefghijklmnopqrstuv", // bcrypt
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$aGFzaA",        // argon2i
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$aGFzaA",       // old version
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$aGFzaA",       // zero iterations
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA",          // bad salt
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",             // empty key
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$aGFzaA$extra", // too many parts
+	} {
+		if _, _, err := h.Verify("password", encoded); err == nil {
+			t.Errorf("Verify(%q) error = nil, want malformed", encoded)
+		}
+	}
+}
+
+func TestNewPasswordHasherRejectsZeroParams(t *testing.T) {
+	for _, cfg := range []config.AuthConfig{
+		{Argon2Memory: 64, Argon2Iterations: 0, Argon2Parallelism: 1},
+		{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 0},
+		{Argon2Memory: 4, Argon2Iterations: 1, Argon2Parallelism: 1},
+	} {
+		if _, err := NewPasswordHasher(cfg); err == nil {
+			t.Errorf("NewPasswordHasher(%+v) error = nil, want invalid params", cfg)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Permission names an action as "<resource>:<verb>", e.g. "users:write".
+// Granted permissions may use "*" for the verb ("users:*") or on their own to
+// mean everything.
+type Permission string
+
+const (
+	PermUsersRead     Permission = "users:read"
+	PermUsersWrite    Permission = "users:write"
+	PermAPIKeysManage Permission = "api_keys:manage"
+)
+
+// ResourceUser is the resource type of a user record, owned by that user.
+const ResourceUser = "user"
+
+// Resource is the thing an action is performed on, for ownership rules.
+type Resource struct {
+	Type    string
+	OwnerID string // compared against Principal.Subject
+}
+
+// Authorizer decides whether the principal on ctx may perform action. A nil
+// resource asks about the action in general, which ownership cannot satisfy.
+// Denials are FORBIDDEN, a missing principal UNAUTHORIZED.
+type Authorizer interface {
+	Authorize(ctx context.Context, action Permission, resource *Resource) error
+}
+
+// Grants reports whether granted covers action.
+func Grants(granted []string, action Permission) bool {
+	res, _, _ := strings.Cut(string(action), ":")
+	for _, g := range granted {
+		switch g {
+		case "*", string(action), res + ":*":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		action  Permission
+		want    bool
+	}{
+		{name: "exact", granted: []string{"users:read"}, action: PermUsersRead, want: true},
+		{name: "resource wildcard", granted: []string{"users:*"}, action: PermUsersWrite, want: true},
+		{name: "everything", granted: []string{"*"}, action: PermAPIKeysManage, want: true},
+		{name: "other verb", granted: []string{"users:read"}, action: PermUsersWrite, want: false},
+		{name: "other resource wildcard", granted: []string{"api_keys:*"}, action: PermUsersRead, want: false},
+		{name: "nothing granted", action: PermUsersRead, want: false},
+		{name: "wildcard needs everything", granted: []string{"users:*"}, action: "*", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Grants(tt.granted, tt.action); got != tt.want {
+				t.Errorf("Grants(%v, %q) = %v, want %v", tt.granted, tt.action, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package auth identifies the caller behind a request. It turns credentials
+// into a [Principal] and carries that principal on the request context; what
+// the principal may do is decided elsewhere.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// PrincipalKind says what sort of credential a principal was established from.
+type PrincipalKind string
+
+const (
+	KindUser   PrincipalKind = "user"
+	KindAPIKey PrincipalKind = "api_key"
+	KindSystem PrincipalKind = "system"
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Kind    PrincipalKind
+	Subject string   // user id for KindUser, key id for KindAPIKey
+	Scopes  []string // as granted by the credential; may be empty
+}
+
+// System is the principal for work the service does on its own behalf, such as
+// background jobs. It is never produced from a request and is allowed anything.
+var System = &Principal{Kind: KindSystem, Subject: "system"}
+
+type principalKeyType struct{}
+
+var principalKey = principalKeyType{}
+
+// WithPrincipal stores p in the context.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// ErrNoCredentials is returned by an [Authenticator] when the request carries
+// no credential of the kind it handles, so the next one can have a go.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator resolves the caller behind a request. A credential that is
+// present but wrong is an error other than [ErrNoCredentials].
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes a user gets at a time.
+	RecoveryCodeCount = 10
+	recoveryCodeLen   = 16 // characters, shown in groups of four
+	recoveryGroupLen  = 4
+)
+
+// recoveryAlphabet leaves out characters that are easy to misread.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes mints RecoveryCodeCount single-use codes of the form
+// "xxxx-xxxx-xxxx-xxxx". Store only [HashRecoveryCode] of each: at about 79
+// bits a code is random enough for a fast hash, as with opaque tokens.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	buf := make([]byte, recoveryCodeLen)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generate recovery codes: %w", err)
+		}
+		for j, b := range buf {
+			buf[j] = recoveryAlphabet[int(b)%len(recoveryAlphabet)]
+		}
+		var code strings.Builder
+		for j := 0; j < recoveryCodeLen; j += recoveryGroupLen {
+			if j > 0 {
+				code.WriteByte('-')
+			}
+			code.Write(buf[j : j+recoveryGroupLen])
+		}
+		codes[i] = code.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes code as typed: case, spaces and dashes do not
+// matter.
+func HashRecoveryCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	return HashOpaqueToken(code)
+}
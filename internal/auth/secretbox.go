@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// secretBoxKeyLen selects AES-256.
+const secretBoxKeyLen = 32
+
+// SecretBox encrypts small secrets, such as TOTP keys, that the server has to
+// read back and so cannot hash. It uses AES-256-GCM.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox takes a base64-encoded 32-byte key, as AUTH_MFA_ENCRYPTION_KEY
+// holds.
+func NewSecretBox(key string) (*SecretBox, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != secretBoxKeyLen {
+		return nil, fmt.Errorf("auth: AUTH_MFA_ENCRYPTION_KEY must be %d bytes, base64-encoded", secretBoxKeyLen)
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. context is authenticated but not stored: Open
+// needs the same value, so binding a secret to its row's ID stops it being
+// copied to another row.
+func (b *SecretBox) Seal(plaintext, context []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("seal: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, context)), nil
+}
+
+// Open decrypts what Seal returned for the same context.
+func (b *SecretBox) Open(sealed string, context []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < b.aead.NonceSize() {
+		return nil, errors.New("open: malformed ciphertext")
+	}
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, context)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBoxKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box, err := NewSecretBox(testBoxKey)
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+
+	sealed, err := box.Seal([]byte("totp key"), []byte("user:1"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if strings.Contains(sealed, "totp key") {
+		t.Fatalf("sealed %q holds the plaintext", sealed)
+	}
+	again, _ := box.Seal([]byte("totp key"), []byte("user:1"))
+	if again == sealed {
+		t.Error("two seals of the same plaintext are identical; nonce reused")
+	}
+
+	got, err := box.Open(sealed, []byte("user:1"))
+	if err != nil || string(got) != "totp key" {
+		t.Errorf("Open() = %q, %v, want %q", got, err, "totp key")
+	}
+}
+
+func TestSecretBoxOpenRejects(t *testing.T) {
+	box, _ := NewSecretBox(testBoxKey)
+	sealed, _ := box.Seal([]byte("totp key"), []byte("user:1"))
+	other, _ := NewSecretBox("ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		name    string
+		box     *SecretBox
+		sealed  string
+		context string
+	}{
+		{name: "other row", box: box, sealed: sealed, context: "user:2"},
+		{name: "other key", box: other, sealed: sealed, context: "user:1"},
+		{name: "tampered", box: box, sealed: string(tampered), context: "user:1"},
+		{name: "not base64", box: box, sealed: "%%%", context: "user:1"},
+		{name: "too short", box: box, sealed: "AAAA", context: "user:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.box.Open(tt.sealed, []byte(tt.context)); err == nil {
+				t.Error("Open() error = nil")
+			}
+		})
+	}
+}
+
+func TestNewSecretBoxKey(t *testing.T) {
+	for _, key := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := NewSecretBox(key); err == nil {
+			t.Errorf("NewSecretBox(%q) error = nil", key)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// opaqueTokenLen is the bytes of entropy in an opaque token.
+const opaqueTokenLen = 32
+
+// GenerateOpaqueToken mints a random bearer string for single-purpose
+// credentials such as refresh tokens. Store only [HashOpaqueToken] of it.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashOpaqueToken is what gets stored and looked up in place of token. The
+// token is random, so a fast hash is enough, as with API keys.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
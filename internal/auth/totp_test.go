@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 appendix B test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Appendix B lists 8-digit codes; 6-digit codes are their last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if got != tt.want {
+			t.Errorf("TOTPCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current", code: TOTPCode(rfcSecret, step), wantStep: step, wantOK: true},
+		{name: "previous step", code: TOTPCode(rfcSecret, step-1), wantStep: step - 1, wantOK: true},
+		{name: "next step", code: TOTPCode(rfcSecret, step+1), wantStep: step + 1, wantOK: true},
+		{name: "surrounding spaces", code: " " + TOTPCode(rfcSecret, step) + " ", wantStep: step, wantOK: true},
+		{name: "two steps old", code: TOTPCode(rfcSecret, step-2)},
+		{name: "wrong", code: "000000"},
+		{name: "too short", code: "12345"},
+		{name: "not digits", code: "12a456"},
+		{name: "empty", code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPKeyURI(t *testing.T) {
+	uri := TOTPKeyURI("Example Co", "ada@example.com", rfcSecret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", uri, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Example Co:ada@example.com" {
+		t.Errorf("uri = %q", uri)
+	}
+	q := u.Query()
+	if q.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || q.Get("issuer") != "Example Co" {
+		t.Errorf("query = %v", q)
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	a, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	b, _ := GenerateTOTPSecret()
+	if len(a) != totpSecretLen || string(a) == string(b) {
+		t.Errorf("secrets %x and %x, want distinct %d-byte keys", a, b, totpSecretLen)
+	}
+}
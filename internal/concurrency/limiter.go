@@ -0,0 +1,183 @@
+// Package concurrency caps how many requests are handled at once, so a spike
+// waits in a short queue or is turned away instead of piling up behind the
+// database pool. The cap is either fixed or adapted with AIMD (additive
+// increase, multiplicative decrease) on observed latency: it grows by one
+// while requests finish within the target and shrinks by a tenth when one
+// does not.
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+)
+
+// backoff is what the limit is multiplied by after a slow request.
+const backoff = 0.9
+
+// ErrOverloaded is returned by [Limiter.Acquire] when the request is shed:
+// the queue was full, or the request waited in it for too long.
+var ErrOverloaded = errors.New("concurrency: overloaded")
+
+// Priority orders the queue: a waiting High request is let in before any
+// waiting Normal one. Each priority has a queue of its own size, so Normal
+// requests filling theirs do not shut High ones out.
+type Priority int
+
+const (
+	Normal Priority = iota
+	High
+)
+
+type Limiter struct {
+	adaptive      bool
+	minLimit      float64
+	maxLimit      float64
+	targetLatency time.Duration
+	queueSizes    [High + 1]int
+	queueTimeout  time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	queued   int
+	queues   [High + 1][]*waiter // FIFO per priority
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func NewLimiter(cfg config.ConcurrencyConfig) (*Limiter, error) {
+	if cfg.Limit <= 0 {
+		return nil, fmt.Errorf("concurrency: limit must be positive")
+	}
+	if cfg.QueueSize < 0 || cfg.HighQueueSize < 0 || cfg.QueueTimeout < 0 {
+		return nil, fmt.Errorf("concurrency: queue size and timeout must not be negative")
+	}
+	l := &Limiter{
+		adaptive:     cfg.Adaptive,
+		minLimit:     float64(cfg.Limit),
+		maxLimit:     float64(cfg.Limit),
+		queueSizes:   [High + 1]int{Normal: cfg.QueueSize, High: cfg.HighQueueSize},
+		queueTimeout: cfg.QueueTimeout,
+		now:          time.Now,
+		limit:        float64(cfg.Limit),
+	}
+	if cfg.Adaptive {
+		if cfg.MinLimit <= 0 || cfg.MinLimit > cfg.Limit || cfg.Limit > cfg.MaxLimit {
+			return nil, fmt.Errorf("concurrency: need 0 < min limit <= limit <= max limit")
+		}
+		if cfg.TargetLatency <= 0 {
+			return nil, fmt.Errorf("concurrency: target latency must be positive")
+		}
+		l.minLimit, l.maxLimit = float64(cfg.MinLimit), float64(cfg.MaxLimit)
+		l.targetLatency = cfg.TargetLatency
+	}
+	return l, nil
+}
+
+// Limit is the current cap on requests in flight.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire lets a request in, waiting in the queue while the limiter is full.
+// Call release when the request is done; its latency feeds an adaptive limit.
+// It fails with [ErrOverloaded] when the request is shed, or with ctx's error
+// when ctx ends first.
+func (l *Limiter) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) && l.queued == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+	if len(l.queues[p]) >= l.queueSizes[p] {
+		l.mu.Unlock()
+		return nil, ErrOverloaded
+	}
+	w := &waiter{ready: make(chan struct{})}
+	l.queues[p] = append(l.queues[p], w)
+	l.queued++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+		return l.releaser(), nil
+	case <-timer.C:
+		err = ErrOverloaded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		// Let in just as we gave up; take the slot rather than leak it.
+		return l.releaser(), nil
+	}
+	for i, queued := range l.queues[p] {
+		if queued == w {
+			l.queues[p] = append(l.queues[p][:i], l.queues[p][i+1:]...)
+			break
+		}
+	}
+	l.queued--
+	return nil, err
+}
+
+func (l *Limiter) releaser() func() {
+	start := l.now()
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.release(l.now().Sub(start)) })
+	}
+}
+
+func (l *Limiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+
+	if l.adaptive {
+		switch {
+		case latency > l.targetLatency:
+			l.limit = math.Max(l.minLimit, l.limit*backoff)
+		// Only grow a limit that is in use, or an idle server would drift to
+		// the maximum and lose its protection.
+		case float64(l.inFlight+1)*2 >= l.limit:
+			l.limit = math.Min(l.maxLimit, l.limit+1)
+		}
+	}
+
+	for l.inFlight < int(l.limit) && l.queued > 0 {
+		w := l.dequeue()
+		w.granted = true
+		l.inFlight++
+		close(w.ready)
+	}
+}
+
+func (l *Limiter) dequeue() *waiter {
+	for p := High; p >= Normal; p-- {
+		if len(l.queues[p]) > 0 {
+			w := l.queues[p][0]
+			l.queues[p] = l.queues[p][1:]
+			l.queued--
+			return w
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+)
+
+func newTestLimiter(t *testing.T, cfg config.ConcurrencyConfig) *Limiter {
+	t.Helper()
+	l, err := NewLimiter(cfg)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	return l
+}
+
+func mustAcquire(t *testing.T, l *Limiter, p Priority) func() {
+	t.Helper()
+	release, err := l.Acquire(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	return release
+}
+
+func TestNewLimiterRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ConcurrencyConfig
+	}{
+		{name: "zero limit", cfg: config.ConcurrencyConfig{}},
+		{name: "negative queue", cfg: config.ConcurrencyConfig{Limit: 1, QueueSize: -1}},
+		{name: "min above limit", cfg: config.ConcurrencyConfig{
+			Limit: 5, Adaptive: true, MinLimit: 10, MaxLimit: 20, TargetLatency: time.Second,
+		}},
+		{name: "limit above max", cfg: config.ConcurrencyConfig{
+			Limit: 50, Adaptive: true, MinLimit: 10, MaxLimit: 20, TargetLatency: time.Second,
+		}},
+		{name: "no target", cfg: config.ConcurrencyConfig{Limit: 10, Adaptive: true, MinLimit: 1, MaxLimit: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLimiter(tt.cfg); err == nil {
+				t.Error("NewLimiter() error = nil")
+			}
+		})
+	}
+}
+
+func TestAcquireShedsWhenQueueFull(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 0, QueueTimeout: time.Second})
+	release := mustAcquire(t, l, Normal)
+
+	if _, err := l.Acquire(context.Background(), Normal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Acquire() error = %v, want ErrOverloaded", err)
+	}
+
+	release()
+	mustAcquire(t, l, Normal)
+}
+
+func TestAcquireShedsAfterQueueTimeout(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond})
+	mustAcquire(t, l, Normal)
+
+	if _, err := l.Acquire(context.Background(), Normal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Acquire() error = %v, want ErrOverloaded", err)
+	}
+	if l.queued != 0 {
+		t.Errorf("queued = %d after timing out, want 0", l.queued)
+	}
+}
+
+func TestAcquireStopsWithContext(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Minute})
+	mustAcquire(t, l, Normal)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.Acquire(ctx, Normal); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestQueuedRequestGetsReleasedSlot(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Minute})
+	release := mustAcquire(t, l, Normal)
+
+	got := make(chan error)
+	go func() {
+		_, err := l.Acquire(context.Background(), Normal)
+		got <- err
+	}()
+	waitQueued(t, l, 1)
+	release()
+	release() // a second call is a no-op
+
+	if err := <-got; err != nil {
+		t.Fatalf("queued Acquire() error = %v", err)
+	}
+	if l.inFlight != 1 {
+		t.Errorf("inFlight = %d, want 1", l.inFlight)
+	}
+}
+
+func TestHighPriorityGoesFirst(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 1, HighQueueSize: 1, QueueTimeout: time.Minute})
+	release := mustAcquire(t, l, Normal)
+
+	order := make(chan Priority, 2)
+	acquire := func(p Priority) {
+		next, err := l.Acquire(context.Background(), p)
+		if err != nil {
+			t.Errorf("Acquire(%d) error = %v", p, err)
+			return
+		}
+		order <- p
+		next()
+	}
+	go acquire(Normal)
+	waitQueued(t, l, 1)
+	// The Normal queue is full, but a High request has its own, served first.
+	go acquire(High)
+	waitQueued(t, l, 2)
+	release()
+
+	if first, second := <-order, <-order; first != High || second != Normal {
+		t.Errorf("order = %d, %d; want High, Normal", first, second)
+	}
+}
+
+func TestHighQueueHasOwnCap(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 1, QueueSize: 0, HighQueueSize: 1, QueueTimeout: time.Minute})
+	mustAcquire(t, l, Normal)
+
+	go func() { _, _ = l.Acquire(context.Background(), High) }()
+	waitQueued(t, l, 1)
+	if _, err := l.Acquire(context.Background(), High); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Acquire(High) error = %v with its queue full, want ErrOverloaded", err)
+	}
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{
+		Limit: 10, Adaptive: true, MinLimit: 5, MaxLimit: 11, TargetLatency: 100 * time.Millisecond,
+	})
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+	observe := func(latency time.Duration) {
+		release := mustAcquire(t, l, Normal)
+		now = now.Add(latency)
+		release()
+	}
+
+	observe(10 * time.Millisecond)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("after a fast request on an idle limiter, Limit() = %d, want 10", got)
+	}
+
+	var busy []func()
+	for range 5 {
+		busy = append(busy, mustAcquire(t, l, Normal))
+	}
+	observe(10 * time.Millisecond)
+	if got := l.Limit(); got != 11 {
+		t.Errorf("after a fast request on a busy limiter, Limit() = %d, want 11", got)
+	}
+	observe(10 * time.Millisecond)
+	if got := l.Limit(); got != 11 {
+		t.Errorf("Limit() = %d, want it held at the maximum 11", got)
+	}
+	observe(time.Second)
+	if got := l.Limit(); got != 9 {
+		t.Errorf("after a slow request, Limit() = %d, want 9", got)
+	}
+
+	for _, release := range busy {
+		release()
+	}
+	for range 20 {
+		observe(time.Second)
+	}
+	if got := l.Limit(); got != 5 {
+		t.Errorf("Limit() = %d, want it held at the minimum 5", got)
+	}
+}
+
+func TestStaticLimitIgnoresLatency(t *testing.T) {
+	l := newTestLimiter(t, config.ConcurrencyConfig{Limit: 10, TargetLatency: time.Millisecond})
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	release := mustAcquire(t, l, Normal)
+	now = now.Add(time.Hour)
+	release()
+
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Limit() = %d, want 10", got)
+	}
+}
+
+func waitQueued(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mu.Lock()
+		queued := l.queued
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -35,8 +35,9 @@ func (c Config) DSN() string {
 }
 
 func New(cfg Config) (*Database, error) {
+	// TranslateError stays off: it swaps *pgconn.PgError for bare sentinels,
+	// losing the constraint the repositories translate by.
 	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
-		TranslateError: true,
 		Logger: gormLogger.NewSlogLogger(slog.Default(), gormLogger.Config{
 			LogLevel:             gormLogger.Warn,
 			ParameterizedQueries: true,
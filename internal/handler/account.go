@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/aarondever/go-gin-template/internal/response"
+	"github.com/aarondever/go-gin-template/internal/service"
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type signupRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type emailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type confirmTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type confirmPasswordResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type AccountHandler struct {
+	svc service.AccountService
+}
+
+func NewAccountHandler(svc service.AccountService) *AccountHandler {
+	return &AccountHandler{svc: svc}
+}
+
+func (h *AccountHandler) Signup(c *gin.Context) {
+	var req signupRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.svc.Signup(c.Request.Context(), &model.User{
+		Name:  req.Name,
+		Email: &req.Email,
+	}, req.Password, c.GetHeader("Accept-Language"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, user)
+}
+
+func (h *AccountHandler) RequestVerification(c *gin.Context) {
+	var req emailRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.RequestVerification(c.Request.Context(), req.Email, c.GetHeader("Accept-Language")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Accepted whether or not an email went out.
+	response.JSON(c, http.StatusAccepted, nil)
+}
+
+func (h *AccountHandler) ConfirmVerification(c *gin.Context) {
+	var req confirmTokenRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.ConfirmVerification(c.Request.Context(), req.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusNoContent, nil)
+}
+
+func (h *AccountHandler) RequestPasswordReset(c *gin.Context) {
+	var req emailRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.RequestPasswordReset(c.Request.Context(), req.Email, c.GetHeader("Accept-Language")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Accepted whether or not an email went out.
+	response.JSON(c, http.StatusAccepted, nil)
+}
+
+func (h *AccountHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req confirmPasswordResetRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Not trimmed: whitespace is part of a password.
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.ConfirmPasswordReset(c.Request.Context(), req.Token, req.Password); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusNoContent, nil)
+}
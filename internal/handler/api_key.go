@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/model"
+	p "github.com/aarondever/go-gin-template/internal/pagination"
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/aarondever/go-gin-template/internal/response"
+	"github.com/aarondever/go-gin-template/internal/service"
+	"github.com/aarondever/go-gin-template/internal/util"
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"dive,required"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"` // gt with no param: in the future
+}
+
+// createAPIKeyResponse is the only place the plaintext key is ever returned.
+type createAPIKeyResponse struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// apiKeyPath is the key a /api-keys/:keyID route acts on.
+type apiKeyPath struct {
+	KeyID uint64 `uri:"keyID"`
+}
+
+type getAPIKeyListRequest struct {
+	p.Pagination
+}
+
+type apiKeyListResponse struct {
+	APIKeys []*model.APIKey `json:"api_keys"`
+	p.Pagination
+}
+
+type APIKeyHandler struct {
+	svc service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(util.TrimStructStr(&req)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, plaintext, err := h.svc.Create(c.Request.Context(), &model.APIKey{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, &createAPIKeyResponse{APIKey: key, Key: plaintext})
+}
+
+func (h *APIKeyHandler) GetList(c *gin.Context) {
+	var req getAPIKeyListRequest
+	if err := params.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	keys, err := h.svc.GetList(c.Request.Context(), &req.Pagination)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, &apiKeyListResponse{
+		APIKeys:    keys,
+		Pagination: req.Pagination,
+	})
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	var path apiKeyPath
+	if err := params.Bind(c, &path); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.Revoke(c.Request.Context(), path.KeyID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusNoContent, nil)
+}
@@ -2,10 +2,10 @@ package handler
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/aarondever/go-gin-template/internal/model"
 	p "github.com/aarondever/go-gin-template/internal/pagination"
+	"github.com/aarondever/go-gin-template/internal/params"
 	"github.com/aarondever/go-gin-template/internal/response"
 	"github.com/aarondever/go-gin-template/internal/service"
 	"github.com/aarondever/go-gin-template/internal/util"
@@ -14,8 +14,9 @@ import (
 )
 
 type createUserRequest struct {
-	Name  string  `json:"name" validate:"required"`
-	Email *string `json:"email" validate:"omitempty,email"`
+	Name     string  `json:"name" validate:"required"`
+	Email    *string `json:"email" validate:"omitempty,email"`
+	Password string  `json:"password" validate:"omitempty,password"`
 }
 
 type updateUserRequest struct {
@@ -23,9 +24,14 @@ type updateUserRequest struct {
 	Email *string `json:"email" validate:"omitempty,email"`
 }
 
+// userPath is the user a /users/:userID route acts on.
+type userPath struct {
+	UserID uint64 `uri:"userID"`
+}
+
 type getUserListRequest struct {
-	Name  string `form:"name"`
-	Email string `form:"email"`
+	Name  string `query:"name"`
+	Email string `query:"email"`
 	p.Pagination
 }
 
@@ -44,12 +50,16 @@ func New(svc service.Service) *Handler {
 
 func (h *Handler) Create(c *gin.Context) {
 	var req createUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := params.BindJSON(c, &req); err != nil {
 		c.Error(err)
 		return
 	}
 
-	if err := validation.ValidateStruct(util.TrimStructStr(req)); err != nil {
+	// Only the profile is trimmed: whitespace is part of a password.
+	password := req.Password
+	util.TrimStructStr(&req)
+	req.Password = password
+	if err := validation.ValidateStruct(req); err != nil {
 		c.Error(err)
 		return
 	}
@@ -57,7 +67,7 @@ func (h *Handler) Create(c *gin.Context) {
 	user, err := h.svc.Create(c.Request.Context(), &model.User{
 		Name:  req.Name,
 		Email: req.Email,
-	})
+	}, req.Password)
 	if err != nil {
 		c.Error(err)
 		return
@@ -67,29 +77,24 @@ func (h *Handler) Create(c *gin.Context) {
 }
 
 func (h *Handler) GetByID(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
-	if err != nil {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
 		c.Error(err)
 		return
 	}
 
-	user, err := h.svc.GetByID(c.Request.Context(), userID)
+	user, err := h.svc.GetByID(c.Request.Context(), path.UserID)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response.JSON(c, http.StatusOK, user)
+	response.ConditionalJSON(c, user, user.UpdatedAt)
 }
 
 func (h *Handler) GetList(c *gin.Context) {
 	var req getUserListRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.Error(err)
-		return
-	}
-
-	if err := validation.ValidateStruct(util.TrimStructStr(req)); err != nil {
+	if err := params.Bind(c, &req); err != nil {
 		c.Error(err)
 		return
 	}
@@ -110,25 +115,25 @@ func (h *Handler) GetList(c *gin.Context) {
 }
 
 func (h *Handler) Update(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
-	if err != nil {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
 		c.Error(err)
 		return
 	}
 
 	var req updateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := params.BindJSON(c, &req); err != nil {
 		c.Error(err)
 		return
 	}
 
-	if err := validation.ValidateStruct(util.TrimStructStr(req)); err != nil {
+	if err := validation.ValidateStruct(util.TrimStructStr(&req)); err != nil {
 		c.Error(err)
 		return
 	}
 
 	user, err := h.svc.Update(c.Request.Context(), &model.User{
-		ID:    userID,
+		ID:    path.UserID,
 		Name:  req.Name,
 		Email: req.Email,
 	})
@@ -141,13 +146,13 @@ func (h *Handler) Update(c *gin.Context) {
 }
 
 func (h *Handler) Delete(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
-	if err != nil {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
 		c.Error(err)
 		return
 	}
 
-	if err := h.svc.Delete(c.Request.Context(), userID); err != nil {
+	if err := h.svc.Delete(c.Request.Context(), path.UserID); err != nil {
 		c.Error(err)
 		return
 	}
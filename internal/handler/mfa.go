@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/aarondever/go-gin-template/internal/response"
+	"github.com/aarondever/go-gin-template/internal/service"
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type disableMFARequest struct {
+	Code string `json:"code"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAHandler struct {
+	svc service.MFAService
+}
+
+func NewMFAHandler(svc service.MFAService) *MFAHandler {
+	return &MFAHandler{svc: svc}
+}
+
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
+		c.Error(err)
+		return
+	}
+
+	enrollment, err := h.svc.EnrollTOTP(c.Request.Context(), path.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	response.JSON(c, http.StatusOK, &totpEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req confirmTOTPRequest
+	if err := params.BindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := h.svc.ConfirmTOTP(c.Request.Context(), path.UserID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	response.JSON(c, http.StatusOK, &recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var path userPath
+	if err := params.Bind(c, &path); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Optional: an administrator resetting another user has no code to send.
+	var req disableMFARequest
+	if c.Request.ContentLength != 0 {
+		if err := params.BindJSON(c, &req); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if err := h.svc.Disable(c.Request.Context(), path.UserID, req.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.JSON(c, http.StatusNoContent, nil)
+}
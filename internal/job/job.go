@@ -0,0 +1,41 @@
+// Package job runs periodic background work alongside the server.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/logger"
+)
+
+// Every runs fn every interval until ctx is done. A failed run is logged and
+// retried at the next tick. Runs are on behalf of [auth.System]. The returned
+// function blocks until a run in progress has finished; call it after
+// canceling ctx and before closing what fn uses.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) (wait func()) {
+	ctx = auth.WithPrincipal(ctx, auth.System)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			start := time.Now()
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				logger.ErrorContext(ctx, "job failed", slog.String("job", name), logger.Err(err))
+				continue
+			}
+			logger.DebugContext(ctx, "job done", slog.String("job", name), slog.Duration("took", time.Since(start)))
+		}
+	})
+	return wg.Wait
+}
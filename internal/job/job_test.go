@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/auth"
+)
+
+func TestEveryRunsUntilCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	var system atomic.Bool
+	enough := make(chan struct{})
+
+	wait := Every(ctx, "test", time.Millisecond, func(ctx context.Context) error {
+		p, _ := auth.PrincipalFrom(ctx)
+		system.Store(p == auth.System)
+		// A failing run must not stop the schedule.
+		if runs.Add(1) == 3 {
+			close(enough)
+		}
+		return errors.New("boom")
+	})
+
+	select {
+	case <-enough:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run three times")
+	}
+	cancel()
+	wait()
+
+	after := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != after {
+		t.Error("job kept running after wait returned")
+	}
+	if !system.Load() {
+		t.Error("job did not run as the system principal")
+	}
+}
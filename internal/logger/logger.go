@@ -92,6 +92,11 @@ func ErrorContext(ctx context.Context, msg string, attrs ...slog.Attr) {
 	slog.LogAttrs(ctx, slog.LevelError, msg, attrs...)
 }
 
+// LogContext logs msg at level, using ctx, for levels decided at run time.
+func LogContext(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	slog.LogAttrs(ctx, level, msg, attrs...)
+}
+
 // Panic logs msg at [slog.LevelError] and then calls [panic](msg).
 func Panic(msg string, attrs ...slog.Attr) {
 	slog.LogAttrs(context.Background(), slog.LevelError, msg, attrs...)
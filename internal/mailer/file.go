@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/util"
+)
+
+type fileDrop struct {
+	dir  string
+	from string
+}
+
+// NewFileDrop writes each message to dir as an .eml file instead of sending
+// it, for local development. Open the files in any mail client.
+func NewFileDrop(dir, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+	return &fileDrop{dir: dir, from: from}, nil
+}
+
+func (m *fileDrop) Send(_ context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := encode(m.from, msg, now)
+	if err != nil {
+		return fmt.Errorf("file drop: %w", err)
+	}
+	// IDs are time-ordered, so the files sort in sending order.
+	path := filepath.Join(m.dir, util.NewID()+".eml")
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return fmt.Errorf("file drop: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aarondever/go-gin-template/config"
+)
+
+func TestFileDropWritesParsableMessage(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileDrop(dir, "App <app@example.com>")
+	if err != nil {
+		t.Fatalf("NewFileDrop() error = %v", err)
+	}
+
+	err = m.Send(context.Background(), &Message{
+		To:      "ada@example.com",
+		Subject: "Héllo\r\nBcc: evil@example.com",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %d files, want 1", len(files))
+	}
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	if got := msg.Header.Get("To"); got != "<ada@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("subject injected a Bcc header: %q", got)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Héllo Bcc: evil@example.com" {
+		t.Errorf("Subject = %q", subject)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Content-Type: %v", err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		b, _ := io.ReadAll(quotedprintable.NewReader(part))
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(b))
+	}
+	want := []string{`text/plain; charset="utf-8": plain body`, `text/html; charset="utf-8": <p>html body</p>`}
+	if strings.Join(bodies, "|") != strings.Join(want, "|") {
+		t.Errorf("parts = %q, want %q", bodies, want)
+	}
+}
+
+func TestEncodeRejectsBadRecipient(t *testing.T) {
+	m := &fileDrop{dir: t.TempDir(), from: "app@example.com"}
+	if err := m.Send(context.Background(), &Message{To: "not an address"}); err == nil {
+		t.Error("Send() error = nil, want a bad recipient error")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	_ = m.Send(context.Background(), &Message{To: "a@example.com"})
+	_ = m.Send(context.Background(), &Message{To: "b@example.com"})
+
+	sent := m.Sent()
+	if len(sent) != 2 || sent[0].To != "a@example.com" || sent[1].To != "b@example.com" {
+		t.Errorf("Sent() = %+v", sent)
+	}
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.MailConfig
+	}{
+		{name: "bad from", cfg: config.MailConfig{Driver: "file", From: "nope", FileDir: t.TempDir()}},
+		{name: "smtp without host", cfg: config.MailConfig{Driver: "smtp", From: "app@example.com"}},
+		{name: "unknown driver", cfg: config.MailConfig{Driver: "carrier-pigeon", From: "app@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New() error = nil, want a config error")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Template names.
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+// templateFS holds templates/<locale>/<name>.txt, which defines "subject" and
+// "text", and an optional templates/<locale>/<name>.html.
+//
+//go:embed templates
+var templateFS embed.FS
+
+type localeTemplates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// Templates renders messages in the recipient's language, falling back to a
+// default locale for languages without a translation.
+type Templates struct {
+	fallback string
+	locales  map[string]*localeTemplates
+	matcher  language.Matcher
+	tags     []language.Tag
+}
+
+// NewTemplates parses the embedded templates. fallback must be one of the
+// locales shipped.
+func NewTemplates(fallback string) (*Templates, error) {
+	t := &Templates{fallback: fallback, locales: make(map[string]*localeTemplates)}
+
+	dirs, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+	for _, dir := range dirs {
+		locale := dir.Name()
+		lt, err := parseLocale(locale)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: locale %s: %w", locale, err)
+		}
+		t.locales[locale] = lt
+		t.tags = append(t.tags, language.Make(locale))
+	}
+	if _, ok := t.locales[fallback]; !ok {
+		return nil, fmt.Errorf("mailer: no templates for default locale %q", fallback)
+	}
+
+	// The fallback goes first: the matcher returns it when nothing matches.
+	for i, tag := range t.tags {
+		if tag == language.Make(fallback) {
+			t.tags[0], t.tags[i] = t.tags[i], t.tags[0]
+		}
+	}
+	t.matcher = language.NewMatcher(t.tags)
+	return t, nil
+}
+
+func parseLocale(locale string) (*localeTemplates, error) {
+	lt := &localeTemplates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	files, err := fs.Glob(templateFS, path.Join("templates", locale, "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name, ext, _ := strings.Cut(path.Base(file), ".")
+		switch ext {
+		case "txt":
+			tmpl, err := texttemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return nil, err
+			}
+			if tmpl.Lookup("subject") == nil || tmpl.Lookup("text") == nil {
+				return nil, fmt.Errorf("%s must define subject and text", file)
+			}
+			lt.text[name] = tmpl
+		case "html":
+			tmpl, err := htmltemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return nil, err
+			}
+			lt.html[name] = tmpl
+		}
+	}
+	return lt, nil
+}
+
+// Match picks the best supported locale for an Accept-Language header value.
+func (t *Templates) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return t.fallback
+	}
+	_, i, _ := t.matcher.Match(tags...)
+	return t.tags[i].String()
+}
+
+// Render fills template name in locale with data and addresses it to to. An
+// unknown locale, or one without that template, uses the fallback.
+func (t *Templates) Render(locale, name, to string, data any) (*Message, error) {
+	lt, ok := t.locales[locale]
+	if !ok || lt.text[name] == nil {
+		lt = t.locales[t.fallback]
+	}
+	text, ok := lt.text[name]
+	if !ok {
+		return nil, fmt.Errorf("mailer: no template %q", name)
+	}
+
+	msg := &Message{To: to}
+	var buf bytes.Buffer
+	if err := text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, fmt.Errorf("mailer: render %s subject: %w", name, err)
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.ExecuteTemplate(&buf, "text", data); err != nil {
+		return nil, fmt.Errorf("mailer: render %s text: %w", name, err)
+	}
+	msg.Text = strings.TrimSpace(buf.String()) + "\n"
+
+	if html, ok := lt.html[name]; ok {
+		buf.Reset()
+		if err := html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("mailer: render %s html: %w", name, err)
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
+
+// LinkData is what the verification and reset templates are filled with.
+type LinkData struct {
+	Name  string
+	Link  string
+	Hours int // until the link expires
+}
@@ -0,0 +1,88 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTemplates(t *testing.T) *Templates {
+	t.Helper()
+	tmpl, err := NewTemplates("en")
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	return tmpl
+}
+
+func TestTemplatesMatch(t *testing.T) {
+	tmpl := newTemplates(t)
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "es-MX,es;q=0.9,en;q=0.8", want: "es"},
+		{header: "fr-FR,en;q=0.5", want: "en"},
+		{header: "en;q=0.2,es;q=0.9", want: "es"},
+		{header: "de", want: "en"},
+		{header: "", want: "en"},
+		{header: "not a language ;;", want: "en"},
+	}
+
+	for _, tt := range tests {
+		if got := tmpl.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// Every locale ships every template, in text and HTML, and each renders.
+func TestTemplatesRenderEveryLocale(t *testing.T) {
+	tmpl := newTemplates(t)
+	data := LinkData{Name: "Ada", Link: "https://app.example.com/x?token=abc&y=1", Hours: 48}
+
+	for locale := range tmpl.locales {
+		for _, name := range []string{TemplateVerifyEmail, TemplateResetPassword} {
+			msg, err := tmpl.Render(locale, name, "ada@example.com", data)
+			if err != nil {
+				t.Errorf("%s/%s: Render() error = %v", locale, name, err)
+				continue
+			}
+			if msg.To != "ada@example.com" || msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("%s/%s: header fields = %q, %q", locale, name, msg.To, msg.Subject)
+			}
+			if !strings.Contains(msg.Text, data.Link) || !strings.Contains(msg.Text, "Ada") {
+				t.Errorf("%s/%s: text lacks name or link:\n%s", locale, name, msg.Text)
+			}
+			// html/template escapes the & in the link attribute.
+			if !strings.Contains(msg.HTML, "token=abc&amp;y=1") {
+				t.Errorf("%s/%s: html lacks escaped link:\n%s", locale, name, msg.HTML)
+			}
+		}
+	}
+}
+
+func TestTemplatesRenderFallsBack(t *testing.T) {
+	tmpl := newTemplates(t)
+
+	msg, err := tmpl.Render("xx", TemplateResetPassword, "ada@example.com", LinkData{Hours: 1})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject != "Reset your password" {
+		t.Errorf("Subject = %q, want the English fallback", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "expires in 1 hour ") {
+		t.Errorf("text does not use the singular:\n%s", msg.Text)
+	}
+
+	if _, err := tmpl.Render("en", "no_such_template", "ada@example.com", nil); err == nil {
+		t.Error("Render(unknown template) error = nil")
+	}
+}
+
+func TestNewTemplatesUnknownFallback(t *testing.T) {
+	if _, err := NewTemplates("xx"); err == nil {
+		t.Error("NewTemplates(xx) error = nil, want an error")
+	}
+}
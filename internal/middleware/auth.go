@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate tries each authenticator in turn and stores the first principal
+// found on the request context. A request with no credentials, or a bad one,
+// is aborted with UNAUTHORIZED; only a rejected credential is challenged with
+// invalid_token.
+func Authenticate(authenticators ...auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, a := range authenticators {
+			principal, err := a.Authenticate(c.Request)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				// A failing authenticator is not the credentials' fault.
+				if e.From(err).Code == e.CodeUnauthorized {
+					c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				}
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", "Bearer")
+		_ = c.Error(e.New(e.CodeUnauthorized, "authentication required"))
+		c.Abort()
+	}
+}
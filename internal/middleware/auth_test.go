@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// authFunc adapts a function to auth.Authenticator.
+type authFunc func(r *http.Request) (*auth.Principal, error)
+
+func (f authFunc) Authenticate(r *http.Request) (*auth.Principal, error) { return f(r) }
+
+func noCredentials() auth.Authenticator {
+	return authFunc(func(*http.Request) (*auth.Principal, error) { return nil, auth.ErrNoCredentials })
+}
+
+func authenticatesAs(subject string) auth.Authenticator {
+	return authFunc(func(*http.Request) (*auth.Principal, error) {
+		return &auth.Principal{Kind: auth.KindUser, Subject: subject}, nil
+	})
+}
+
+func TestAuthenticateStoresPrincipal(t *testing.T) {
+	var got *auth.Principal
+	engine := newEngine(ErrorHandler(ErrorOptions{}), Authenticate(noCredentials(), authenticatesAs("42")))
+	engine.GET("/resource", func(c *gin.Context) {
+		got, _ = auth.PrincipalFrom(c.Request.Context())
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got == nil || got.Subject != "42" {
+		t.Errorf("principal = %+v, want subject 42", got)
+	}
+}
+
+// The first authenticator that recognises the credentials wins.
+func TestAuthenticateFirstMatchWins(t *testing.T) {
+	var got *auth.Principal
+	engine := newEngine(ErrorHandler(ErrorOptions{}), Authenticate(authenticatesAs("first"), authenticatesAs("second")))
+	engine.GET("/resource", func(c *gin.Context) {
+		got, _ = auth.PrincipalFrom(c.Request.Context())
+	})
+
+	do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	if got == nil || got.Subject != "first" {
+		t.Errorf("principal = %+v, want subject first", got)
+	}
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	tests := []struct {
+		name           string
+		authenticators []auth.Authenticator
+		wantStatus     int
+		wantCode       e.Code
+		wantChallenge  string
+	}{
+		{
+			name:           "no credentials",
+			authenticators: []auth.Authenticator{noCredentials()},
+			wantStatus:     http.StatusUnauthorized,
+			wantCode:       e.CodeUnauthorized,
+			wantChallenge:  "Bearer",
+		},
+		{
+			name:          "no authenticators",
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      e.CodeUnauthorized,
+			wantChallenge: "Bearer",
+		},
+		{
+			name: "rejected credentials",
+			authenticators: []auth.Authenticator{authFunc(func(*http.Request) (*auth.Principal, error) {
+				return nil, e.New(e.CodeUnauthorized, "token expired")
+			})},
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      e.CodeUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			// A broken authenticator is a server fault, not the caller's.
+			name: "authenticator failure",
+			authenticators: []auth.Authenticator{authFunc(func(*http.Request) (*auth.Principal, error) {
+				return nil, errors.New("lookup failed")
+			})},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   e.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			engine := newEngine(ErrorHandler(ErrorOptions{}), Authenticate(tt.authenticators...))
+			engine.GET("/resource", func(c *gin.Context) { called = true })
+
+			w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+			if called {
+				t.Error("handler ran, want the chain aborted")
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if body := decodeErrorBody(t, w); body.Error.Code != tt.wantCode {
+				t.Errorf("body code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceFunc describes the resource a request acts on, for ownership rules.
+type ResourceFunc func(c *gin.Context) *auth.Resource
+
+// PathOwner describes a resource of resourceType owned by the subject named in
+// path parameter param, e.g. PathOwner(auth.ResourceUser, "userID").
+func PathOwner(resourceType, param string) ResourceFunc {
+	return func(c *gin.Context) *auth.Resource {
+		return &auth.Resource{Type: resourceType, OwnerID: c.Param(param)}
+	}
+}
+
+// Authorize aborts the request unless the authenticated principal may perform
+// action. resource may be nil when the route has no owner to check against.
+// It must run after [Authenticate].
+func Authorize(az auth.Authorizer, action auth.Permission, resource ResourceFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var res *auth.Resource
+		if resource != nil {
+			res = resource(c)
+		}
+		if err := az.Authorize(c.Request.Context(), action, res); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
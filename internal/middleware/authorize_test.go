@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingAuthorizer remembers what it was asked and answers with err.
+type recordingAuthorizer struct {
+	action   auth.Permission
+	resource *auth.Resource
+	err      error
+}
+
+func (a *recordingAuthorizer) Authorize(_ context.Context, action auth.Permission, resource *auth.Resource) error {
+	a.action, a.resource = action, resource
+	return a.err
+}
+
+func TestAuthorizeAllows(t *testing.T) {
+	az := &recordingAuthorizer{}
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.PUT("/users/:userID", Authorize(az, auth.PermUsersWrite, PathOwner(auth.ResourceUser, "userID")),
+		func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := do(engine, httptest.NewRequest(http.MethodPut, "/users/42", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if az.action != auth.PermUsersWrite {
+		t.Errorf("action = %q, want %q", az.action, auth.PermUsersWrite)
+	}
+	if az.resource == nil || *az.resource != (auth.Resource{Type: auth.ResourceUser, OwnerID: "42"}) {
+		t.Errorf("resource = %+v, want user owned by 42", az.resource)
+	}
+}
+
+func TestAuthorizeWithoutResource(t *testing.T) {
+	az := &recordingAuthorizer{}
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.GET("/users", Authorize(az, auth.PermUsersRead, nil), func(c *gin.Context) {})
+
+	do(engine, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if az.resource != nil {
+		t.Errorf("resource = %+v, want nil", az.resource)
+	}
+}
+
+func TestAuthorizeDenies(t *testing.T) {
+	az := &recordingAuthorizer{err: e.New(e.CodeForbidden, "permission denied")}
+	handlerRan := false
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.DELETE("/users/:userID", Authorize(az, auth.PermUsersWrite, nil), func(c *gin.Context) {
+		handlerRan = true
+	})
+
+	w := do(engine, httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if body := decodeErrorBody(t, w); body.Error.Code != e.CodeForbidden {
+		t.Errorf("code = %q, want %q", body.Error.Code, e.CodeForbidden)
+	}
+	if handlerRan {
+		t.Error("handler ran after denial")
+	}
+}
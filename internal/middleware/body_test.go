@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON is a handler that binds its body the way real handlers do.
+func bindJSON(c *gin.Context) {
+	var req map[string]any
+	if err := params.BindJSON(c, &req); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+func TestBodyLimit(t *testing.T) {
+	small := `{"name":"Ada"}`
+	large := `{"name":"` + strings.Repeat("a", 100) + `"}`
+
+	tests := []struct {
+		name       string
+		body       string
+		chunked    bool
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "within", body: small, wantStatus: http.StatusOK, wantCalled: true},
+		{name: "declared over", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		// No Content-Length: the limit trips while binding reads.
+		{name: "streamed over", body: large, chunked: true, wantStatus: http.StatusRequestEntityTooLarge, wantCalled: true},
+		{name: "streamed within", body: small, chunked: true, wantStatus: http.StatusOK, wantCalled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			called := false
+			engine := newEngine(ErrorHandler(ErrorOptions{}), BodyLimit(64))
+			engine.POST("/resource", func(c *gin.Context) {
+				called = true
+				bindJSON(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/resource", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.Body = io.NopCloser(req.Body)
+			}
+			w := do(engine, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantStatus != http.StatusOK {
+				body := decodeErrorBody(t, w)
+				if body.Error.Code != e.CodePayloadTooLarge || body.Error.Details["limit"] != "64" {
+					t.Errorf("error = %+v, want PAYLOAD_TOO_LARGE with limit 64", body.Error)
+				}
+			}
+		})
+	}
+}
+
+// A route's own limit replaces its group's, in either direction.
+func TestBodyLimitNested(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	engine := newEngine(ErrorHandler(ErrorOptions{}), BodyLimit(64))
+	engine.POST("/raised", BodyLimit(1024), bindJSON)
+	engine.POST("/lowered", BodyLimit(8), bindJSON)
+
+	req := httptest.NewRequest(http.MethodPost, "/raised", strings.NewReader(body))
+	req.ContentLength = -1
+	if w := do(engine, req); w.Code != http.StatusOK {
+		t.Errorf("raised: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	captureLogs(t)
+	w := do(engine, httptest.NewRequest(http.MethodPost, "/lowered", strings.NewReader(`{"a":"bc"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("lowered: status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "json", body: "{}", contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "json with charset", body: "{}", contentType: "application/json; charset=utf-8", wantStatus: http.StatusOK},
+		{name: "upper case", body: "{}", contentType: "Application/JSON", wantStatus: http.StatusOK},
+		{name: "json suffix", body: "{}", contentType: "application/merge-patch+json", wantStatus: http.StatusOK},
+		{name: "no body", wantStatus: http.StatusOK},
+		{name: "no body with other type", contentType: "text/plain", wantStatus: http.StatusOK},
+		{name: "form", body: "a=b", contentType: "application/x-www-form-urlencoded", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "text", body: "{}", contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "missing", body: "{}", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed", body: "{}", contentType: "application/json;;", wantStatus: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			engine := newEngine(ErrorHandler(ErrorOptions{}), RequireJSON())
+			engine.POST("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+			req := httptest.NewRequest(http.MethodPost, "/resource", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := do(engine, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body := decodeErrorBody(t, w); body.Error.Code != e.CodeUnsupportedMediaType {
+					t.Errorf("code = %s, want %s", body.Error.Code, e.CodeUnsupportedMediaType)
+				}
+			}
+		})
+	}
+}
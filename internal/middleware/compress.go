@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is what gzip, brotli and zstd writers have in common.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encodings in order of preference when the client likes several equally.
+var encodings = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{name: "zstd", pool: &sync.Pool{New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return enc
+	}}},
+	{name: "br", pool: &sync.Pool{New: func() any {
+		// Level 4 is about as fast as gzip and still smaller.
+		return brotli.NewWriterLevel(nil, 4)
+	}}},
+	{name: "gzip", pool: &sync.Pool{New: func() any {
+		return gzip.NewWriter(nil)
+	}}},
+}
+
+// Compress encodes responses of at least minSize bytes with the best of zstd,
+// brotli and gzip that the client accepts. Bodies that are already encoded,
+// are not text-like, or are too small go out as they are. A handler that
+// flushes is streaming, so its response is compressed whatever its size, and
+// each flush reaches the client.
+func Compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		i := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if i < 0 {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: i, minSize: minSize}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding returns the index in encodings of the coding to use for an
+// Accept-Encoding value, or -1 for none.
+func negotiateEncoding(header string) int {
+	if header == "" {
+		return -1
+	}
+	q := make([]float64, len(encodings))
+	for i := range q {
+		q[i] = -1 // not mentioned
+	}
+	wildcard := -1.0
+	for part := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			weight = f
+		}
+		if name == "*" {
+			wildcard = weight
+			continue
+		}
+		for i, enc := range encodings {
+			if enc.name == name {
+				q[i] = weight
+			}
+		}
+	}
+
+	best, bestQ := -1, 0.0
+	for i := range encodings {
+		weight := q[i]
+		if weight < 0 {
+			weight = wildcard
+		}
+		if weight > bestQ {
+			best, bestQ = i, weight
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the first minSize bytes to decide whether to
+// compress, then either encodes or passes everything through.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding int
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow commits to a response without a body.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.start(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return w.status != 0 || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.start(true)
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start sends the headers, encoding the body from here on if big says it is
+// large enough and the response suits compression.
+func (w *compressWriter) start(big bool) error {
+	w.decided = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if big && w.compressible() {
+		h := w.Header()
+		h.Set("Content-Encoding", encodings[w.encoding].name)
+		h.Del("Content-Length")
+		// The encoded bytes differ, so a strong validator no longer applies.
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = encodings[w.encoding].pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish sends what is still held back and ends the encoded stream.
+func (w *compressWriter) finish() {
+	if !w.decided && (w.status != 0 || len(w.buf) > 0) {
+		_ = w.start(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(nil)
+		encodings[w.encoding].pool.Put(w.enc)
+		w.enc = nil
+	}
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	switch status := w.ResponseWriter.Status(); {
+	case status < 200, status == http.StatusNoContent, status == http.StatusNotModified,
+		status == http.StatusPartialContent:
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript"
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		r = gz
+	case "br":
+		r = brotli.NewReader(body)
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatalf("zstd.NewReader() error = %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "":
+		r = body
+	default:
+		t.Fatalf("unexpected encoding %q", encoding)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "identity", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "gzip, deflate, br", want: "br"},
+		{header: "gzip, deflate, br, zstd", want: "zstd"},
+		{header: "GZIP", want: "gzip"},
+		{header: "br;q=0.5, gzip;q=0.8", want: "gzip"},
+		{header: "zstd;q=0, gzip", want: "gzip"},
+		{header: "*", want: "zstd"},
+		{header: "*;q=0.5, br", want: "br"},
+		{header: "*, zstd;q=0", want: "br"},
+		{header: "gzip;q=0", want: ""},
+		{header: "gzip;q=bad", want: ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if i := negotiateEncoding(tt.header); i >= 0 {
+			got = encodings[i].name
+		}
+		if got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"Ada Lovelace"},`, 100)
+
+	tests := []struct {
+		name         string
+		accept       string
+		handler      gin.HandlerFunc
+		wantEncoding string
+		wantBody     string
+	}{
+		{
+			name:         "gzip",
+			accept:       "gzip",
+			handler:      func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) },
+			wantEncoding: "gzip",
+			wantBody:     large,
+		},
+		{
+			name:         "brotli",
+			accept:       "gzip, br",
+			handler:      func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) },
+			wantEncoding: "br",
+			wantBody:     large,
+		},
+		{
+			name:         "zstd",
+			accept:       "gzip, br, zstd",
+			handler:      func(c *gin.Context) { c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(large)) },
+			wantEncoding: "zstd",
+			wantBody:     large,
+		},
+		{
+			name:     "not accepted",
+			handler:  func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) },
+			wantBody: large,
+		},
+		{
+			name:     "small",
+			accept:   "gzip",
+			handler:  func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(`{"ok":true}`)) },
+			wantBody: `{"ok":true}`,
+		},
+		{
+			name:     "already compressed type",
+			accept:   "gzip",
+			handler:  func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) },
+			wantBody: large,
+		},
+		{
+			name:   "already encoded",
+			accept: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Encoding", "identity-ish")
+				c.Data(http.StatusOK, "application/json", []byte(large))
+			},
+			wantEncoding: "identity-ish",
+			wantBody:     large,
+		},
+		{
+			name:   "many small writes",
+			accept: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Type", "text/plain")
+				c.Status(http.StatusOK)
+				for range 100 {
+					_, _ = c.Writer.WriteString(`{"name":"Ada Lovelace"},`)
+				}
+			},
+			wantEncoding: "gzip",
+			wantBody:     large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newEngine(Compress(1024))
+			engine.GET("/resource", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := do(engine, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			encoding := w.Header().Get("Content-Encoding")
+			if encoding != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			if encoding == "identity-ish" {
+				encoding = ""
+			}
+			if got := decode(t, encoding, w.Body); got != tt.wantBody {
+				t.Errorf("decoded body has %d bytes, want %d", len(got), len(tt.wantBody))
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want %q", got, "Accept-Encoding")
+			}
+			if tt.wantEncoding != "" && tt.wantEncoding != "identity-ish" && w.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length kept on an encoded response")
+			}
+		})
+	}
+}
+
+func TestCompressWeakensETag(t *testing.T) {
+	engine := newEngine(Compress(10))
+	engine.GET("/resource", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.Data(http.StatusOK, "application/json", []byte(strings.Repeat("a", 100)))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	w := do(engine, req)
+
+	if got := w.Header().Get("ETag"); got != `W/"v1"` {
+		t.Errorf("ETag = %q, want %q", got, `W/"v1"`)
+	}
+}
+
+// Error envelopes written after the handler go through the same writer.
+func TestCompressErrorEnvelope(t *testing.T) {
+	captureLogs(t)
+	engine := newEngine(Compress(10), ErrorHandler(ErrorOptions{}))
+	engine.GET("/resource", failWith(e.New(e.CodeNotFound, "user not found")))
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	w := do(engine, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := decode(t, w.Header().Get("Content-Encoding"), w.Body); !strings.Contains(got, "NOT_FOUND") {
+		t.Errorf("body = %q, want the envelope", got)
+	}
+}
+
+func TestCompressNoBody(t *testing.T) {
+	engine := newEngine(Compress(0))
+	engine.DELETE("/resource", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	req := httptest.NewRequest(http.MethodDelete, "/resource", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	w := do(engine, req)
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("got %d, %q, encoding %q; want a bare 204", w.Code, w.Body, w.Header().Get("Content-Encoding"))
+	}
+}
+
+// Each flush must reach the client decodable, before the handler returns.
+func TestCompressStreams(t *testing.T) {
+	next := make(chan struct{})
+	engine := newEngine(Compress(1024))
+	engine.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		for _, line := range []string{"one\n", "two\n"} {
+			_, _ = c.Writer.WriteString(line)
+			c.Writer.Flush()
+			<-next
+		}
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	lines := bufio.NewReader(gz)
+	for _, want := range []string{"one\n", "two\n"} {
+		got, err := lines.ReadString('\n')
+		if err != nil || got != want {
+			t.Fatalf("read %q, %v; want %q", got, err, want)
+		}
+		next <- struct{}{}
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/concurrency"
+	"github.com/gin-gonic/gin"
+)
+
+// shedRetryAfter is what a shed request is told to wait; by then the queue
+// has turned over many times.
+const shedRetryAfter = time.Second
+
+// PriorityFunc picks the queue a request waits in.
+type PriorityFunc func(c *gin.Context) concurrency.Priority
+
+// ConcurrencyLimit lets requests through limiter, queuing them while it is
+// full, and sheds those it turns away with UNAVAILABLE and a Retry-After.
+func ConcurrencyLimit(limiter *concurrency.Limiter, priority PriorityFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, err := limiter.Acquire(c.Request.Context(), priority(c))
+		if err != nil {
+			if errors.Is(err, concurrency.ErrOverloaded) {
+				err = e.Wrap(err, e.CodeUnavailable, "server is overloaded").WithRetryAfter(shedRetryAfter)
+			}
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
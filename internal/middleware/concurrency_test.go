@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/concurrency"
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrencyLimitShedsExcess(t *testing.T) {
+	limiter, err := concurrency.NewLimiter(config.ConcurrencyConfig{Limit: 1, QueueSize: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	normal := func(*gin.Context) concurrency.Priority { return concurrency.Normal }
+	started, finish := make(chan struct{}), make(chan struct{})
+	engine := newEngine(ErrorHandler(ErrorOptions{}), ConcurrencyLimit(limiter, normal))
+	engine.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/fast", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	firstDone := make(chan *httptest.ResponseRecorder)
+	go func() { firstDone <- do(engine, httptest.NewRequest(http.MethodGet, "/slow", nil)) }()
+	<-started
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	close(finish)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if body := decodeErrorBody(t, w); body.Error.Code != e.CodeUnavailable {
+		t.Errorf("code = %q, want %q", body.Error.Code, e.CodeUnavailable)
+	}
+	if first := <-firstDone; first.Code != http.StatusNoContent {
+		t.Errorf("first status = %d, want %d", first.Code, http.StatusNoContent)
+	}
+
+	// The slot is free again.
+	if w := do(engine, httptest.NewRequest(http.MethodGet, "/fast", nil)); w.Code != http.StatusNoContent {
+		t.Errorf("after release, status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestConcurrencyLimitQueuesBriefly(t *testing.T) {
+	limiter, err := concurrency.NewLimiter(config.ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	normal := func(*gin.Context) concurrency.Priority { return concurrency.Normal }
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	engine := newEngine(ErrorHandler(ErrorOptions{}), ConcurrencyLimit(limiter, normal))
+	engine.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/fast", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	go do(engine, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	<-started
+	time.AfterFunc(10*time.Millisecond, func() { close(finish) })
+
+	if w := do(engine, httptest.NewRequest(http.MethodGet, "/fast", nil)); w.Code != http.StatusNoContent {
+		t.Errorf("queued request status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -1,21 +1,198 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
 	"github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
+// CORS answers cross-origin requests from the origins in cfg. An allowed
+// origin is echoed back, never replaced by "*", so credentials work; they are
+// only allowed with exact origins. A
+// preflight asking for an origin, method or header outside cfg fails with
+// FORBIDDEN; other requests from unknown origins go through without CORS
+// headers, which keeps the browser from reading the response.
+func CORS(cfg config.CORSConfig) (gin.HandlerFunc, error) {
+	origins, err := newOriginMatcher(cfg.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+	if origins.any && cfg.AllowCredentials {
+		return nil, fmt.Errorf("cors: allowing credentials from any origin is unsafe; list the origins")
+	}
+	// A pattern too loose by one character would hand out users' sessions.
+	if (len(origins.wildcards) > 0 || len(origins.patterns) > 0) && cfg.AllowCredentials {
+		return nil, fmt.Errorf("cors: allowing credentials from wildcard or regex origins is unsafe; list the origins")
+	}
+
+	methods := upperAll(cfg.AllowedMethods)
+	allowMethods := strings.Join(methods, ", ")
+	anyHeader := slices.Contains(cfg.AllowedHeaders, "*")
+	headers := lowerAll(cfg.AllowedHeaders)
+	allowHeaders := strings.Join(trimAll(cfg.AllowedHeaders), ", ")
+	exposeHeaders := strings.Join(trimAll(cfg.ExposedHeaders), ", ")
+	maxAge := strconv.Itoa(ceilSeconds(cfg.MaxAge))
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		requestMethod := c.GetHeader("Access-Control-Request-Method")
+		if c.Request.Method != http.MethodOptions || requestMethod == "" {
+			if origins.match(origin) {
+				h.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+			}
+			c.Next()
+			return
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		// A preflight: answer it here, whatever routes exist.
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		requestHeaders := splitList(c.GetHeader("Access-Control-Request-Headers"))
+		if !origins.match(origin) ||
+			!slices.Contains(methods, strings.ToUpper(requestMethod)) ||
+			(!anyHeader && !allLowerIn(requestHeaders, headers)) {
+			_ = c.Error(e.New(e.CodeForbidden, "cross-origin request not allowed"))
+			c.Abort()
 			return
 		}
 
-		c.Next()
+		h.Set("Access-Control-Allow-Origin", origin)
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		h.Set("Access-Control-Allow-Methods", allowMethods)
+		if anyHeader {
+			h.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+		} else if allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}, nil
+}
+
+// originMatcher holds CORS_ALLOWED_ORIGINS: exact origins, "*", origins with
+// one "*" standing for subdomains, and "regex:" patterns.
+type originMatcher struct {
+	any       bool
+	exact     map[string]struct{}
+	wildcards [][2]string // prefix, suffix
+	patterns  []*regexp.Regexp
+}
+
+func newOriginMatcher(specs []string) (*originMatcher, error) {
+	m := &originMatcher{exact: map[string]struct{}{}}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		switch {
+		case spec == "":
+		case spec == "*":
+			m.any = true
+		case strings.HasPrefix(spec, "regex:"):
+			re, err := regexp.Compile("^(?:" + strings.TrimPrefix(spec, "regex:") + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("cors: origin %q: %w", spec, err)
+			}
+			m.patterns = append(m.patterns, re)
+		case strings.Count(spec, "*") == 1:
+			prefix, suffix, _ := strings.Cut(strings.ToLower(spec), "*")
+			m.wildcards = append(m.wildcards, [2]string{prefix, suffix})
+		case strings.Contains(spec, "*"):
+			return nil, fmt.Errorf("cors: origin %q has more than one *", spec)
+		default:
+			m.exact[strings.ToLower(spec)] = struct{}{}
+		}
+	}
+	return m, nil
+}
+
+func (m *originMatcher) match(origin string) bool {
+	if m.any {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if _, ok := m.exact[lower]; ok {
+		return true
+	}
+	for _, w := range m.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			// The * covers host labels only, not a path, port or userinfo.
+			if !strings.ContainsAny(lower[len(w[0]):len(lower)-len(w[1])], "/:@") {
+				return true
+			}
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated header value, dropping blanks.
+func splitList(v string) []string {
+	var items []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func allLowerIn(items, allowed []string) bool {
+	for _, item := range items {
+		if !slices.Contains(allowed, strings.ToLower(item)) {
+			return false
+		}
+	}
+	return true
+}
+
+func trimAll(s []string) []string {
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+func upperAll(s []string) []string {
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	return out
+}
+
+func lowerAll(s []string) []string {
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = strings.ToLower(strings.TrimSpace(v))
 	}
+	return out
 }
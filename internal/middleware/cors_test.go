@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,151 +29,236 @@ func do(engine *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
 	return w
 }
 
-const (
-	allowHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With"
-	allowMethods = "POST, OPTIONS, GET, PUT, DELETE"
-)
+var testCORSConfig = config.CORSConfig{
+	AllowedOrigins: []string{
+		"https://app.example.com",
+		"https://*.preview.example.com",
+		`regex:https://pr-\d+\.example\.dev`,
+	},
+	AllowedMethods: []string{"GET", "POST", "PATCH"},
+	AllowedHeaders: []string{"Authorization", "Content-Type"},
+	ExposedHeaders: []string{"X-Request-ID", "Retry-After"},
+	MaxAge:         10 * time.Minute,
+}
 
-// wantCORSHeaders is the full set the middleware writes on every response.
-var wantCORSHeaders = map[string]string{
-	"Access-Control-Allow-Origin":      "*",
-	"Access-Control-Allow-Credentials": "true",
-	"Access-Control-Allow-Headers":     allowHeaders,
-	"Access-Control-Allow-Methods":     allowMethods,
+func newCORSEngine(t *testing.T, cfg config.CORSConfig) (*gin.Engine, *bool) {
+	t.Helper()
+	cors, err := CORS(cfg)
+	if err != nil {
+		t.Fatalf("CORS() error = %v", err)
+	}
+	called := false
+	engine := newEngine(ErrorHandler(ErrorOptions{}), cors)
+	engine.GET("/resource", func(c *gin.Context) {
+		called = true
+		c.String(http.StatusOK, "ok")
+	})
+	return engine, &called
+}
+
+func corsRequest(method, origin string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/resource", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
 }
 
-func assertCORSHeaders(t *testing.T, w *httptest.ResponseRecorder) {
+func assertHeaders(t *testing.T, w *httptest.ResponseRecorder, want map[string]string) {
 	t.Helper()
-	for key, want := range wantCORSHeaders {
-		if got := w.Header().Get(key); got != want {
-			t.Errorf("header %s = %q, want %q", key, got, want)
+	for key, v := range want {
+		if got := w.Header().Get(key); got != v {
+			t.Errorf("header %s = %q, want %q", key, got, v)
 		}
 	}
 }
 
-func TestCORSSetsHeaders(t *testing.T) {
-	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
-
-	for _, method := range methods {
-		t.Run(method, func(t *testing.T) {
-			called := false
-			engine := newEngine(CORS())
-			engine.Handle(method, "/resource", func(c *gin.Context) {
-				called = true
-				c.String(http.StatusOK, "ok")
-			})
+func TestCORSOriginMatching(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "HTTPS://APP.EXAMPLE.COM", allowed: true},
+		{origin: "http://app.example.com"},
+		{origin: "https://app.example.com.evil.com"},
+		{origin: "https://a.preview.example.com", allowed: true},
+		{origin: "https://a.b.preview.example.com", allowed: true},
+		{origin: "https://preview.example.com"},
+		{origin: "https://evil.com/.preview.example.com"},
+		{origin: "https://evil.com:443.preview.example.com"},
+		{origin: "https://pr-42.example.dev", allowed: true},
+		{origin: "https://pr-42.example.dev.evil.com"},
+		{origin: "https://pr-x.example.dev"},
+		{origin: "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			engine, called := newCORSEngine(t, testCORSConfig)
 
-			w := do(engine, httptest.NewRequest(method, "/resource", nil))
+			w := do(engine, corsRequest(http.MethodGet, tt.origin, nil))
 
-			if !called {
-				t.Error("handler was not called")
+			if !*called || w.Code != http.StatusOK {
+				t.Fatalf("status = %d, called = %v; want the request served either way", w.Code, *called)
 			}
-			if w.Code != http.StatusOK {
-				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want %q", got, "Origin")
 			}
-			if body := w.Body.String(); body != "ok" {
-				t.Errorf("body = %q, want %q", body, "ok")
+			if !tt.allowed {
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+					t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+				}
+				return
 			}
-			assertCORSHeaders(t, w)
+			assertHeaders(t, w, map[string]string{
+				"Access-Control-Allow-Origin":      tt.origin,
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Expose-Headers":    "X-Request-ID, Retry-After",
+			})
 		})
 	}
 }
 
-func TestCORSPreflight(t *testing.T) {
-	called := false
-	engine := newEngine(CORS())
-	engine.Handle(http.MethodOptions, "/resource", func(c *gin.Context) {
-		called = true
-		c.String(http.StatusOK, "should not run")
-	})
+func TestCORSWithoutOrigin(t *testing.T) {
+	engine, _ := newCORSEngine(t, testCORSConfig)
 
-	w := do(engine, httptest.NewRequest(http.MethodOptions, "/resource", nil))
+	w := do(engine, corsRequest(http.MethodGet, "", nil))
 
-	if called {
-		t.Error("handler ran, want the preflight to short-circuit it")
-	}
-	if w.Code != http.StatusNoContent {
-		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none on a same-origin request", got)
 	}
-	if body := w.Body.String(); body != "" {
-		t.Errorf("body = %q, want empty", body)
+	// Caches must still keep this response apart from cross-origin ones.
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want %q", got, "Origin")
 	}
-	// The browser needs the headers on the preflight response above all.
-	assertCORSHeaders(t, w)
 }
 
-// Preflights routinely target paths that have no OPTIONS route registered; gin
-// runs the global middleware for those through its no-route chain.
-func TestCORSPreflightWithoutRoute(t *testing.T) {
-	engine := newEngine(CORS())
-	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+// Preflights routinely target paths with no OPTIONS route; gin runs global
+// middleware for those through its no-route chain.
+func TestCORSPreflight(t *testing.T) {
+	cfg := testCORSConfig
+	cfg.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.AllowCredentials = true
+	engine, called := newCORSEngine(t, cfg)
 
-	w := do(engine, httptest.NewRequest(http.MethodOptions, "/resource", nil))
+	w := do(engine, corsRequest(http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "PATCH",
+		"Access-Control-Request-Headers": "content-type, authorization",
+	}))
 
-	if w.Code != http.StatusNoContent {
-		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	if *called {
+		t.Error("handler ran, want the preflight answered by the middleware")
+	}
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("status = %d, body %q; want 204 and empty", w.Code, w.Body.String())
+	}
+	assertHeaders(t, w, map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, PATCH",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+		"Access-Control-Max-Age":           "600",
+	})
+	if got := w.Header().Values("Vary"); len(got) != 3 {
+		t.Errorf("Vary = %q, want Origin and both request headers", got)
 	}
-	assertCORSHeaders(t, w)
 }
 
-// The preflight check compares against the canonical upper-case method, so a
-// lower-case one is just an unrouted request that still gets the headers.
-func TestCORSLowercaseOptionsIsNotPreflight(t *testing.T) {
-	engine := newEngine(CORS())
-	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+func TestCORSPreflightRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+	}{
+		{name: "origin", origin: "https://evil.com", method: "GET"},
+		{name: "method", origin: "https://app.example.com", method: "DELETE"},
+		{name: "header", origin: "https://app.example.com", method: "GET", headers: "Authorization, X-Custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := newCORSEngine(t, testCORSConfig)
 
-	w := do(engine, httptest.NewRequest("options", "/resource", nil))
+			w := do(engine, corsRequest(http.MethodOptions, tt.origin, map[string]string{
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": tt.headers,
+			}))
 
-	if w.Code == http.StatusNoContent {
-		t.Errorf("status = %d, want the request not to be treated as a preflight", w.Code)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+			if body := decodeErrorBody(t, w); body.Error.Code != e.CodeForbidden {
+				t.Errorf("code = %s, want %s", body.Error.Code, e.CodeForbidden)
+			}
+			for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods"} {
+				if got := w.Header().Get(h); got != "" {
+					t.Errorf("%s = %q, want none", h, got)
+				}
+			}
+		})
 	}
-	assertCORSHeaders(t, w)
 }
 
-// Aborting must stop the rest of the chain, not just the final handler.
-func TestCORSPreflightAbortsLaterMiddleware(t *testing.T) {
+// An OPTIONS request without Access-Control-Request-Method is not a preflight.
+func TestCORSPlainOptionsIsNotPreflight(t *testing.T) {
 	reached := false
-	engine := newEngine(CORS(), func(c *gin.Context) {
-		reached = true
-		c.Next()
-	})
-	engine.Handle(http.MethodOptions, "/resource", func(c *gin.Context) {})
+	cors, _ := CORS(testCORSConfig)
+	engine := newEngine(cors, func(c *gin.Context) { reached = true })
+	engine.OPTIONS("/resource", func(c *gin.Context) {})
 
-	w := do(engine, httptest.NewRequest(http.MethodOptions, "/resource", nil))
+	do(engine, corsRequest(http.MethodOptions, "https://app.example.com", nil))
 
-	if reached {
-		t.Error("downstream middleware ran, want the chain aborted")
-	}
-	if w.Code != http.StatusNoContent {
-		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	if !reached {
+		t.Error("the chain was cut short, want the request routed")
 	}
 }
 
-// Headers are written before the handler runs, so they survive a handler error.
-func TestCORSHeadersSurviveHandlerError(t *testing.T) {
-	engine := newEngine(CORS())
-	engine.GET("/resource", func(c *gin.Context) {
-		c.String(http.StatusInternalServerError, "boom")
+func TestCORSAnyOriginAndHeader(t *testing.T) {
+	engine, _ := newCORSEngine(t, config.CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+		AllowedHeaders: []string{"*"},
 	})
 
-	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+	w := do(engine, corsRequest(http.MethodOptions, "https://anyone.test", map[string]string{
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Custom",
+	}))
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
 	}
-	assertCORSHeaders(t, w)
+	assertHeaders(t, w, map[string]string{
+		"Access-Control-Allow-Origin":      "https://anyone.test",
+		"Access-Control-Allow-Credentials": "",
+		"Access-Control-Allow-Headers":     "X-Custom",
+		"Access-Control-Max-Age":           "",
+	})
 }
 
-// The Origin request header is ignored; the response is always a wildcard.
-func TestCORSIgnoresRequestOrigin(t *testing.T) {
-	engine := newEngine(CORS())
-	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
-
-	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
-	req.Header.Set("Origin", "https://evil.example.com")
-	w := do(engine, req)
-
-	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
-		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "*")
+func TestCORSConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.CORSConfig
+	}{
+		{name: "credentials with any origin", cfg: config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{name: "credentials with a wildcard origin", cfg: config.CORSConfig{
+			AllowedOrigins: []string{"https://app.example.com", "https://*.example.com"}, AllowCredentials: true,
+		}},
+		{name: "credentials with a regex origin", cfg: config.CORSConfig{
+			AllowedOrigins: []string{`regex:https://pr-\d+\.example\.dev`}, AllowCredentials: true,
+		}},
+		{name: "bad regex", cfg: config.CORSConfig{AllowedOrigins: []string{"regex:("}}},
+		{name: "two wildcards", cfg: config.CORSConfig{AllowedOrigins: []string{"https://*.*.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CORS(tt.cfg); err == nil {
+				t.Error("CORS() error = nil, want one")
+			}
+		})
 	}
 }
@@ -3,8 +3,12 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	e "github.com/aarondever/go-gin-template/internal/apperror"
 
@@ -12,16 +16,39 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-var statusByCode = map[e.Code]int{
-	e.CodeInvalidInput: http.StatusBadRequest,
-	e.CodeUnauthorized: http.StatusUnauthorized,
-	e.CodeForbidden:    http.StatusForbidden,
-	e.CodeNotFound:     http.StatusNotFound,
-	e.CodeConflict:     http.StatusConflict,
-	e.CodeRateLimited:  http.StatusTooManyRequests,
-	e.CodeCanceled:     499, // Client Closed Request
-	e.CodeTimeout:      http.StatusGatewayTimeout,
-	e.CodeInternal:     http.StatusInternalServerError,
+// ErrorOptions shape the responses ErrorHandler writes. The zero value writes
+// English envelopes; a new option's zero value must keep the behaviour from
+// before it, so existing callers need no change.
+type ErrorOptions struct {
+	// Format is used when the client's Accept header does not choose one.
+	Format ErrorFormat
+	// LegacyValidationDetails also reports validation violations the old way,
+	// as details mapping each field's name to the rule it broke.
+	LegacyValidationDetails bool
+	// Localizer, when set, translates messages into the language the client's
+	// Accept-Language header asks for. Logs stay in English.
+	Localizer *e.Localizer
+}
+
+// ErrorFormat is how ErrorHandler renders a failure.
+type ErrorFormat string
+
+const (
+	// ErrorFormatEnvelope is {"error":{"code":…,"message":…}} as application/json.
+	ErrorFormatEnvelope ErrorFormat = "envelope"
+	// ErrorFormatProblem is RFC 9457 problem details as application/problem+json.
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+const problemContentType = "application/problem+json"
+
+// ParseErrorFormat checks a configured format name.
+func ParseErrorFormat(s string) (ErrorFormat, error) {
+	switch f := ErrorFormat(s); f {
+	case ErrorFormatEnvelope, ErrorFormatProblem:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown error format %q, want %q or %q", s, ErrorFormatEnvelope, ErrorFormatProblem)
 }
 
 type errorResponse struct {
@@ -29,12 +56,34 @@ type errorResponse struct {
 }
 
 type errorBody struct {
-	Code    e.Code            `json:"code"`
-	Message string            `json:"message"`
-	Details map[string]string `json:"details,omitempty"`
+	Code       e.Code            `json:"code"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+	Violations []e.Violation     `json:"violations,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`  // the same request may succeed later
+	RequestID  string            `json:"request_id,omitempty"` // quote it when reporting the failure
 }
 
-func ErrorHandler() gin.HandlerFunc {
+// problem is an RFC 9457 problem details object. The type is about:blank, so
+// the title is the status text; code, details, violations, retryable and
+// request_id are extension members carrying what the envelope does.
+type problem struct {
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	Status     int               `json:"status"`
+	Detail     string            `json:"detail"`
+	Instance   string            `json:"instance"`
+	Code       e.Code            `json:"code"`
+	Details    map[string]string `json:"details,omitempty"`
+	Violations []e.Violation     `json:"violations,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+}
+
+// ErrorHandler turns the last error a handler recorded into a logged failure
+// and a response, in opts.Format unless the client's Accept header prefers
+// the other one, and in the client's language when opts.Localizer is set.
+func ErrorHandler(opts ErrorOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
@@ -45,9 +94,13 @@ func ErrorHandler() gin.HandlerFunc {
 		err := c.Errors.Last().Err
 		appErr := e.From(err)
 
-		status, ok := statusByCode[appErr.Code]
-		if !ok {
-			status = http.StatusInternalServerError
+		// An unregistered code is reported as INTERNAL is, but keeps its name.
+		def, _ := e.Lookup(appErr.Code)
+		status := def.Status
+		if appErr.Message == "" {
+			withDefault := *appErr
+			withDefault.Message = def.Message
+			appErr = &withDefault
 		}
 
 		// Log failed request
@@ -56,14 +109,10 @@ func ErrorHandler() gin.HandlerFunc {
 			slog.Int("status", status),
 			slog.String("method", c.Request.Method),
 			slog.String("path", c.Request.URL.Path),
-			slog.String("query", c.Request.URL.Path),
+			slog.String("query", c.Request.URL.RawQuery),
 			slog.String("error", appErr.Error()),
 		}
-		if status >= 500 {
-			logger.ErrorContext(c.Request.Context(), "request failed", attrs...)
-		} else {
-			logger.WarnContext(c.Request.Context(), "request failed", attrs...)
-		}
+		logger.LogContext(c.Request.Context(), def.LogLevel, "request failed", attrs...)
 
 		// Nothing left to write: the client went away, or a response is committed
 		// and a second write would corrupt it.
@@ -71,12 +120,88 @@ func ErrorHandler() gin.HandlerFunc {
 			return
 		}
 
-		body := errorResponse{Error: errorBody{
-			Code:    appErr.Code,
-			Message: appErr.Message,
-			Details: appErr.Details,
-		}}
+		if appErr.RetryAfter > 0 {
+			// Whole seconds, rounded up so the client does not retry too early.
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(appErr.RetryAfter)))
+		}
+
+		if opts.Localizer != nil {
+			locale := opts.Localizer.Match(c.GetHeader("Accept-Language"))
+			appErr = opts.Localizer.Localize(appErr, locale)
+			c.Header("Content-Language", locale)
+			c.Writer.Header().Add("Vary", "Accept-Language")
+		}
+
+		requestID := RequestIDFrom(c.Request.Context())
+		retryable := def.Retryable || appErr.RetryAfter > 0
+		details := appErr.Details
+		if opts.LegacyValidationDetails && details == nil && len(appErr.Violations) > 0 {
+			details = make(map[string]string, len(appErr.Violations))
+			for _, v := range appErr.Violations {
+				details[v.Field()] = v.Rule
+			}
+		}
+
+		c.Writer.Header().Add("Vary", "Accept")
+		if !wantsProblem(c.GetHeader("Accept"), opts.Format) {
+			c.AbortWithStatusJSON(status, errorResponse{Error: errorBody{
+				Code:       appErr.Code,
+				Message:    appErr.Message,
+				Details:    details,
+				Violations: appErr.Violations,
+				Retryable:  retryable,
+				RequestID:  requestID,
+			}})
+			return
+		}
+
+		// Set first; gin keeps a Content-Type that is already there.
+		c.Header("Content-Type", problemContentType)
+		c.AbortWithStatusJSON(status, problem{
+			Type:       "about:blank",
+			Title:      http.StatusText(status),
+			Status:     status,
+			Detail:     appErr.Message,
+			Instance:   c.Request.URL.Path,
+			Code:       appErr.Code,
+			Details:    details,
+			Violations: appErr.Violations,
+			Retryable:  retryable,
+			RequestID:  requestID,
+		})
+	}
+}
+
+// wantsProblem lets the client's Accept header choose when it ranks
+// application/problem+json and application/json differently, and falls back
+// to the configured format when it does not.
+func wantsProblem(accept string, format ErrorFormat) bool {
+	problemQ, jsonQ := acceptQuality(accept, problemContentType), acceptQuality(accept, "application/json")
+	switch {
+	case problemQ > jsonQ:
+		return true
+	case jsonQ > problemQ:
+		return false
+	}
+	return format == ErrorFormatProblem
+}
 
-		c.AbortWithStatusJSON(status, body)
+// acceptQuality is the weight the Accept header gives mediaType by name;
+// wildcards do not count, since they cannot tell the two formats apart.
+func acceptQuality(accept, mediaType string) float64 {
+	best := 0.0
+	for part := range strings.SplitSeq(accept, ",") {
+		name, params, err := mime.ParseMediaType(part)
+		if err != nil || name != mediaType {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		best = max(best, q)
 	}
+	return best
 }
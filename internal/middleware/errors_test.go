@@ -8,8 +8,10 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	e "github.com/aarondever/go-gin-template/internal/apperror"
 	"github.com/gin-gonic/gin"
@@ -36,7 +38,7 @@ func decodeErrorBody(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
 func TestErrorHandlerNoError(t *testing.T) {
 	rec := captureLogs(t)
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
 
 	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -63,11 +65,14 @@ func TestErrorHandlerStatusByCode(t *testing.T) {
 		{name: "forbidden", code: e.CodeForbidden, wantStatus: http.StatusForbidden},
 		{name: "not found", code: e.CodeNotFound, wantStatus: http.StatusNotFound},
 		{name: "conflict", code: e.CodeConflict, wantStatus: http.StatusConflict},
+		{name: "payload too large", code: e.CodePayloadTooLarge, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "unsupported media type", code: e.CodeUnsupportedMediaType, wantStatus: http.StatusUnsupportedMediaType},
 		{name: "rate limited", code: e.CodeRateLimited, wantStatus: http.StatusTooManyRequests},
 		// 499 is nginx's Client Closed Request; reachable only when the code is
 		// set explicitly, since a real context.Canceled takes the abort path.
 		{name: "canceled", code: e.CodeCanceled, wantStatus: 499},
 		{name: "timeout", code: e.CodeTimeout, wantStatus: http.StatusGatewayTimeout},
+		{name: "unavailable", code: e.CodeUnavailable, wantStatus: http.StatusServiceUnavailable},
 		{name: "internal", code: e.CodeInternal, wantStatus: http.StatusInternalServerError},
 		// Anything not in the table falls back to 500.
 		{name: "unmapped code", code: e.Code("SOMETHING_ELSE"), wantStatus: http.StatusInternalServerError},
@@ -75,7 +80,7 @@ func TestErrorHandlerStatusByCode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			engine := newEngine(ErrorHandler())
+			engine := newEngine(ErrorHandler(ErrorOptions{}))
 			engine.GET("/resource", failWith(e.New(tt.code, "boom")))
 
 			w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -99,7 +104,7 @@ func TestErrorHandlerResponseShape(t *testing.T) {
 	appErr := e.New(e.CodeInvalidInput, "bad request").
 		WithDetails(map[string]string{"email": "required"})
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.POST("/resource", failWith(appErr))
 
 	w := do(engine, httptest.NewRequest(http.MethodPost, "/resource", nil))
@@ -129,9 +134,96 @@ func TestErrorHandlerResponseShape(t *testing.T) {
 	}
 }
 
+func TestErrorHandlerProblemDetails(t *testing.T) {
+	appErr := e.New(e.CodeNotFound, "user not found").
+		WithDetails(map[string]string{"id": "7"})
+	engine := newEngine(RequestID(), ErrorHandler(ErrorOptions{Format: ErrorFormatProblem}))
+	engine.GET("/v1/users/:userID", failWith(appErr))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/7?expand=roles", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := do(engine, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", ct)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode body %q: %v", w.Body.String(), err)
+	}
+	want := map[string]any{
+		"type":       "about:blank",
+		"title":      "Not Found",
+		"status":     float64(http.StatusNotFound),
+		"detail":     "user not found",
+		"instance":   "/v1/users/7",
+		"code":       "NOT_FOUND",
+		"details":    map[string]any{"id": "7"},
+		"request_id": "req-1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("body = %v, want %v", got, want)
+	}
+}
+
+func TestErrorHandlerNegotiatesFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  ErrorFormat
+		accept  string
+		problem bool
+	}{
+		{name: "envelope by default", format: ErrorFormatEnvelope},
+		{name: "problem by config", format: ErrorFormatProblem, problem: true},
+		{name: "wildcard leaves config", format: ErrorFormatProblem, accept: "*/*", problem: true},
+		{name: "client asks for problem", format: ErrorFormatEnvelope, accept: "application/problem+json", problem: true},
+		{name: "client asks for json", format: ErrorFormatProblem, accept: "application/json"},
+		{
+			name: "client ranks problem higher", format: ErrorFormatEnvelope,
+			accept: "application/json;q=0.5, application/problem+json", problem: true,
+		},
+		{name: "client ranks json higher", format: ErrorFormatProblem, accept: "application/problem+json;q=0.1, application/json"},
+		{name: "tie leaves config", format: ErrorFormatEnvelope, accept: "application/problem+json, application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newEngine(ErrorHandler(ErrorOptions{Format: tt.format}))
+			engine.GET("/resource", failWith(e.New(e.CodeNotFound, "missing")))
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			w := do(engine, req)
+
+			problem := w.Header().Get("Content-Type") == "application/problem+json"
+			if problem != tt.problem {
+				t.Errorf("Content-Type = %q, want problem = %v", w.Header().Get("Content-Type"), tt.problem)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+		})
+	}
+}
+
+func TestParseErrorFormat(t *testing.T) {
+	for _, s := range []string{"envelope", "problem"} {
+		if f, err := ParseErrorFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseErrorFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+	if _, err := ParseErrorFormat("xml"); err == nil {
+		t.Error("ParseErrorFormat(\"xml\") error = nil")
+	}
+}
+
 // Details is omitempty, so an error without them produces no such key.
 func TestErrorHandlerOmitsEmptyDetails(t *testing.T) {
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", failWith(e.New(e.CodeNotFound, "missing")))
 
 	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -145,12 +237,37 @@ func TestErrorHandlerOmitsEmptyDetails(t *testing.T) {
 	}
 }
 
+func TestErrorHandlerRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		want       string
+	}{
+		{name: "whole seconds", retryAfter: 2 * time.Minute, want: "120"},
+		{name: "rounded up", retryAfter: 1500 * time.Millisecond, want: "2"},
+		{name: "unset", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newEngine(ErrorHandler(ErrorOptions{}))
+			engine.GET("/resource", failWith(e.New(e.CodeRateLimited, "slow down").WithRetryAfter(tt.retryAfter)))
+
+			w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+			if got := w.Header().Get("Retry-After"); got != tt.want {
+				t.Errorf("Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // The internal cause is logged but never serialized.
 func TestErrorHandlerHidesInternalCause(t *testing.T) {
 	rec := captureLogs(t)
 	cause := errors.New("pq: connection refused to 10.0.0.5")
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", failWith(e.Wrap(cause, e.CodeInternal, "internal server error")))
 
 	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -165,7 +282,7 @@ func TestErrorHandlerHidesInternalCause(t *testing.T) {
 
 // Plain errors are normalised by apperror.From, so handlers can return anything.
 func TestErrorHandlerNormalizesPlainError(t *testing.T) {
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", failWith(errors.New("something broke")))
 
 	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -193,7 +310,7 @@ func TestErrorHandlerValidationError(t *testing.T) {
 		t.Fatal("expected validation to fail")
 	}
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.POST("/resource", failWith(verr))
 
 	w := do(engine, httptest.NewRequest(http.MethodPost, "/resource", nil))
@@ -208,11 +325,122 @@ func TestErrorHandlerValidationError(t *testing.T) {
 	if body.Error.Message != "validation failed" {
 		t.Errorf("body message = %q, want %q", body.Error.Message, "validation failed")
 	}
-	want := map[string]string{"Email": "required", "Age": "gte"}
-	for field, tag := range want {
-		if body.Error.Details[field] != tag {
-			t.Errorf("details[%q] = %q, want %q", field, body.Error.Details[field], tag)
+	want := []e.Violation{
+		{Path: "/Email", Rule: "required", Message: "is required"},
+		{Path: "/Age", Rule: "gte", Param: "18", Message: "must be at least 18"},
+	}
+	if !reflect.DeepEqual(body.Error.Violations, want) {
+		t.Errorf("body violations = %+v, want %+v", body.Error.Violations, want)
+	}
+	if body.Error.Details != nil {
+		t.Errorf("body details = %v, want none", body.Error.Details)
+	}
+}
+
+// A handler binding with gin directly still gets INVALID_INPUT for malformed
+// JSON or a value of the wrong type. (A truncated body fails with a bare
+// io.ErrUnexpectedEOF, which only params.BindJSON knows came from the body.)
+func TestErrorHandlerBindingError(t *testing.T) {
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.POST("/resource", func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(err)
 		}
+	})
+
+	tests := []struct {
+		body string
+		want []e.Violation
+	}{
+		{body: `{"name" "Ada"}`},
+		{body: `{"name":5}`, want: []e.Violation{{Path: "/name", Rule: "type", Param: "string", Message: "must be a string"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/resource", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := do(engine, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			body := decodeErrorBody(t, w)
+			if body.Error.Code != e.CodeInvalidInput || !reflect.DeepEqual(body.Error.Violations, tt.want) {
+				t.Errorf("body = %s %+v, want %s %+v", body.Error.Code, body.Error.Violations, e.CodeInvalidInput, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerLegacyValidationDetails(t *testing.T) {
+	violations := []e.Violation{
+		{Path: "/addresses/0/zip", Rule: "required", Message: "is required"},
+		{Path: "/name", Rule: "max", Param: "100", Message: "must be at most 100 characters"},
+	}
+	engine := newEngine(ErrorHandler(ErrorOptions{LegacyValidationDetails: true}))
+	engine.POST("/invalid", failWith(e.New(e.CodeInvalidInput, "validation failed").WithViolations(violations)))
+	engine.POST("/too-large", failWith(e.New(e.CodePayloadTooLarge, "too large").
+		WithDetails(map[string]string{"limit": "10"})))
+
+	body := decodeErrorBody(t, do(engine, httptest.NewRequest(http.MethodPost, "/invalid", nil)))
+	if want := map[string]string{"zip": "required", "name": "max"}; !reflect.DeepEqual(body.Error.Details, want) {
+		t.Errorf("body details = %v, want %v", body.Error.Details, want)
+	}
+	if !reflect.DeepEqual(body.Error.Violations, violations) {
+		t.Errorf("body violations = %+v, want them kept", body.Error.Violations)
+	}
+
+	// Errors with details of their own keep them.
+	body = decodeErrorBody(t, do(engine, httptest.NewRequest(http.MethodPost, "/too-large", nil)))
+	if want := map[string]string{"limit": "10"}; !reflect.DeepEqual(body.Error.Details, want) {
+		t.Errorf("body details = %v, want %v", body.Error.Details, want)
+	}
+}
+
+func TestErrorHandlerLocalizes(t *testing.T) {
+	type payload struct {
+		Email string `json:"email" validate:"required"`
+	}
+	verr := validator.New().Struct(payload{})
+
+	localizer, err := e.NewLocalizer("en")
+	if err != nil {
+		t.Fatalf("NewLocalizer() error = %v", err)
+	}
+	engine := newEngine(ErrorHandler(ErrorOptions{Localizer: localizer}))
+	engine.POST("/invalid", failWith(verr))
+
+	req := httptest.NewRequest(http.MethodPost, "/invalid", nil)
+	req.Header.Set("Accept-Language", "es-ES,es;q=0.9,en;q=0.5")
+	w := do(engine, req)
+
+	body := decodeErrorBody(t, w)
+	if body.Error.Message != "la validación ha fallado" {
+		t.Errorf("body message = %q, want it in Spanish", body.Error.Message)
+	}
+	want := []e.Violation{{Path: "/Email", Rule: "required", Message: "es obligatorio"}}
+	if !reflect.DeepEqual(body.Error.Violations, want) {
+		t.Errorf("body violations = %+v, want %+v", body.Error.Violations, want)
+	}
+	if got := w.Header().Get("Content-Language"); got != "es" {
+		t.Errorf("Content-Language = %q, want es", got)
+	}
+	if got := w.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Accept-Language", "Accept"}) {
+		t.Errorf("Vary = %v, want Accept-Language and Accept", got)
+	}
+
+	// Without a supported language, the fallback.
+	req = httptest.NewRequest(http.MethodPost, "/invalid", nil)
+	req.Header.Set("Accept-Language", "de")
+	w = do(engine, req)
+	if body := decodeErrorBody(t, w); body.Error.Message != "validation failed" {
+		t.Errorf("body message = %q, want it in English", body.Error.Message)
+	}
+	if got := w.Header().Get("Content-Language"); got != "en" {
+		t.Errorf("Content-Language = %q, want en", got)
 	}
 }
 
@@ -220,7 +448,7 @@ func TestErrorHandlerValidationError(t *testing.T) {
 func TestErrorHandlerWrappedAppError(t *testing.T) {
 	appErr := e.New(e.CodeNotFound, "user not found")
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", failWith(fmt.Errorf("service: %w", appErr)))
 
 	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -247,7 +475,7 @@ func TestErrorHandlerClientCanceled(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := captureLogs(t)
 
-			engine := newEngine(ErrorHandler())
+			engine := newEngine(ErrorHandler(ErrorOptions{}))
 			engine.GET("/resource", failWith(tt.err))
 
 			w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -275,7 +503,7 @@ func TestErrorHandlerClientCanceled(t *testing.T) {
 func TestErrorHandlerDoesNotOverwriteWrittenResponse(t *testing.T) {
 	rec := captureLogs(t)
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", func(c *gin.Context) {
 		c.String(http.StatusCreated, "partial payload")
 		_ = c.Error(e.New(e.CodeInternal, "failed midway"))
@@ -297,7 +525,7 @@ func TestErrorHandlerDoesNotOverwriteWrittenResponse(t *testing.T) {
 }
 
 func TestErrorHandlerUsesLastError(t *testing.T) {
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource", func(c *gin.Context) {
 		_ = c.Error(e.New(e.CodeInvalidInput, "first"))
 		_ = c.Error(e.New(e.CodeConflict, "second"))
@@ -331,7 +559,7 @@ func TestErrorHandlerLogLevelBySeverity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := captureLogs(t)
 
-			engine := newEngine(ErrorHandler())
+			engine := newEngine(ErrorHandler(ErrorOptions{}))
 			engine.GET("/resource", failWith(e.New(tt.code, "boom")))
 
 			do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
@@ -346,7 +574,7 @@ func TestErrorHandlerLogLevelBySeverity(t *testing.T) {
 func TestErrorHandlerLogAttributes(t *testing.T) {
 	rec := captureLogs(t)
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.POST("/users/42", failWith(e.New(e.CodeNotFound, "user not found")))
 
 	do(engine, httptest.NewRequest(http.MethodPost, "/users/42?verbose=1", nil))
@@ -367,16 +595,12 @@ func TestErrorHandlerLogAttributes(t *testing.T) {
 	if got := entry.str(t, "path"); got != "/users/42" {
 		t.Errorf("attr path = %q, want %q", got, "/users/42")
 	}
+	if got := entry.str(t, "query"); got != "verbose=1" {
+		t.Errorf("attr query = %q, want %q", got, "verbose=1")
+	}
 	if got := entry.str(t, "error"); got != "user not found" {
 		t.Errorf("attr error = %q, want %q", got, "user not found")
 	}
-
-	// BUG: errors.go passes c.Request.URL.Path for the "query" attribute, so
-	// the query string is never logged. Pinned here so the behaviour change is
-	// deliberate; the fix is to use c.Request.URL.RawQuery.
-	if got := entry.str(t, "query"); got != "/users/42" {
-		t.Errorf("attr query = %q, want the currently duplicated path %q", got, "/users/42")
-	}
 }
 
 // c.Error only records; it does not stop the chain. A handler that wants to bail
@@ -385,7 +609,7 @@ func TestErrorHandlerLogAttributes(t *testing.T) {
 func TestErrorHandlerRecordingDoesNotAbortChain(t *testing.T) {
 	reached := false
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource",
 		failWith(e.New(e.CodeForbidden, "no access")),
 		func(c *gin.Context) {
@@ -409,7 +633,7 @@ func TestErrorHandlerRecordingDoesNotAbortChain(t *testing.T) {
 func TestErrorHandlerRespondsAfterHandlerAbort(t *testing.T) {
 	reached := false
 
-	engine := newEngine(ErrorHandler())
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
 	engine.GET("/resource",
 		func(c *gin.Context) {
 			_ = c.Error(e.New(e.CodeUnauthorized, "missing token"))
@@ -434,18 +658,63 @@ func TestErrorHandlerRespondsAfterHandlerAbort(t *testing.T) {
 	}
 }
 
-func TestErrorHandlerStatusByCodeTableCoversAllCodes(t *testing.T) {
-	codes := []e.Code{
-		e.CodeInvalidInput, e.CodeUnauthorized, e.CodeForbidden, e.CodeNotFound,
-		e.CodeConflict, e.CodeRateLimited, e.CodeCanceled, e.CodeTimeout, e.CodeInternal,
+// codeTeapot is declared the way a package adds its own code.
+var codeTeapot = e.Register(e.Definition{
+	Code: "TEAPOT", Status: http.StatusTeapot, Message: "short and stout", LogLevel: slog.LevelInfo,
+})
+
+// Every registered code gets its status and log level from the registry,
+// including codes declared outside apperror.
+func TestErrorHandlerUsesRegistry(t *testing.T) {
+	for _, def := range e.Definitions() {
+		t.Run(string(def.Code), func(t *testing.T) {
+			rec := captureLogs(t)
+			engine := newEngine(ErrorHandler(ErrorOptions{}))
+			engine.GET("/resource", failWith(e.New(def.Code, "boom")))
+
+			w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+			if w.Code != def.Status {
+				t.Errorf("status = %d, want %d", w.Code, def.Status)
+			}
+			if body := decodeErrorBody(t, w); body.Error.Retryable != def.Retryable {
+				t.Errorf("body retryable = %v, want %v", body.Error.Retryable, def.Retryable)
+			}
+			if entry := rec.only(t); entry.Level != def.LogLevel {
+				t.Errorf("log level = %v, want %v", entry.Level, def.LogLevel)
+			}
+		})
+	}
+	if _, ok := e.Lookup(codeTeapot); !ok {
+		t.Errorf("Lookup(%s) found nothing, want the registered code", codeTeapot)
 	}
+}
 
-	for _, code := range codes {
-		if _, ok := statusByCode[code]; !ok {
-			t.Errorf("statusByCode has no entry for %q", code)
-		}
+func TestErrorHandlerDefaultMessage(t *testing.T) {
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.GET("/teapot", failWith(e.New(codeTeapot, "")))
+	engine.GET("/unmapped", failWith(e.New("SOMETHING_ELSE", "")))
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/teapot", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTeapot)
 	}
-	if len(statusByCode) != len(codes) {
-		t.Errorf("statusByCode has %d entries, want %d", len(statusByCode), len(codes))
+	if body := decodeErrorBody(t, w); body.Error.Message != "short and stout" {
+		t.Errorf("body message = %q, want the code's default", body.Error.Message)
+	}
+
+	body := decodeErrorBody(t, do(engine, httptest.NewRequest(http.MethodGet, "/unmapped", nil)))
+	if body.Error.Code != "SOMETHING_ELSE" || body.Error.Message != "internal server error" {
+		t.Errorf("body = %+v, want the code kept with INTERNAL's message", body.Error)
+	}
+}
+
+// An error asking the client to come back is retryable whatever its code.
+func TestErrorHandlerRetryableWithRetryAfter(t *testing.T) {
+	engine := newEngine(ErrorHandler(ErrorOptions{}))
+	engine.POST("/busy", failWith(e.New(e.CodeConflict, "in progress").WithRetryAfter(time.Second)))
+
+	if body := decodeErrorBody(t, do(engine, httptest.NewRequest(http.MethodPost, "/busy", nil))); !body.Error.Retryable {
+		t.Error("body retryable = false, want true with Retry-After")
 	}
 }
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLen      = 255
+	idempotencyInProgressWait = time.Second
+)
+
+// Idempotency makes POST and PATCH requests that carry an Idempotency-Key
+// safe to retry. The first request with a key runs and, if it succeeds, its
+// response is stored for cfg.TTL; a retry with the same key and body gets that
+// response again, marked Idempotent-Replayed. The same key with a different
+// request fails with CONFLICT, as does a retry while the first is still
+// running. A request that fails is not stored, so its retry runs again.
+//
+// Keys belong to the caller, so mount it after Authenticate.
+func Idempotency(keys repository.IdempotencyRepository, cfg config.IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			_ = c.Error(e.New(e.CodeInvalidInput, "invalid Idempotency-Key header"))
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		ctx := c.Request.Context()
+		now := time.Now()
+		claim := &model.IdempotencyKey{
+			Key:         ByCaller(c) + ":" + key,
+			Fingerprint: fingerprint(c.Request, body),
+			LockedUntil: now.Add(cfg.LockTimeout),
+			ExpiresAt:   now.Add(cfg.TTL),
+			CreatedAt:   now,
+		}
+		holder, err := keys.Acquire(ctx, claim, now)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if holder != nil {
+			switch {
+			case holder.Fingerprint != claim.Fingerprint:
+				_ = c.Error(e.New(e.CodeConflict, "Idempotency-Key was used with a different request"))
+			case holder.StatusCode == 0:
+				_ = c.Error(e.New(e.CodeConflict, "a request with this Idempotency-Key is in progress").
+					WithRetryAfter(idempotencyInProgressWait))
+			default:
+				replay(c, holder)
+			}
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer, before: c.Writer.Header().Clone()}
+		c.Writer = w
+		// The key is stored or released after the handler, when the request
+		// context may be over.
+		ctx = context.WithoutCancel(ctx)
+		stored := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if stored {
+				return
+			}
+			if err := keys.Release(ctx, claim.Key); err != nil {
+				logger.ErrorContext(ctx, "failed to release idempotency key", logger.Err(err))
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) > 0 || !w.Written() || w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		if err := keys.Complete(ctx, claim.Key, w.Status(), w.header, w.body.Bytes()); err != nil {
+			// The response is already out; the retry will run the request again.
+			logger.ErrorContext(ctx, "failed to store idempotent response", logger.Err(err))
+			return
+		}
+		stored = true
+	}
+}
+
+func replay(c *gin.Context, stored *model.IdempotencyKey) {
+	for k, v := range stored.Header {
+		c.Writer.Header()[k] = v
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(stored.StatusCode)
+	if _, err := c.Writer.Write(stored.Body); err != nil {
+		logger.DebugContext(c.Request.Context(), "failed to replay response", slog.Int("status", stored.StatusCode), logger.Err(err))
+	}
+}
+
+// validIdempotencyKey accepts up to 255 visible ASCII characters; clients
+// usually send a UUID.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter copies the response as it is written. It keeps only the
+// headers set after the middleware ran, so a replay leaves out what outer
+// middleware adds afresh (request ID, rate limits, compression). They are
+// taken when the response is committed, not at WriteHeader: gin sets
+// Content-Type in between.
+type recordingWriter struct {
+	gin.ResponseWriter
+	before http.Header
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) snapshot() {
+	if w.header != nil {
+		return
+	}
+	w.header = http.Header{}
+	for k, v := range w.ResponseWriter.Header() {
+		if !slices.Equal(w.before[k], v) {
+			w.header[k] = slices.Clone(v)
+		}
+	}
+}
+
+func (w *recordingWriter) WriteHeaderNow() {
+	w.snapshot()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.snapshot()
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Flush() {
+	w.snapshot()
+	w.ResponseWriter.Flush()
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeIdempotencyRepo keeps keys in memory with the same claiming rules as
+// the Postgres repository.
+type fakeIdempotencyRepo struct {
+	mu   sync.Mutex
+	keys map[string]*model.IdempotencyKey
+}
+
+func newFakeIdempotencyRepo() *fakeIdempotencyRepo {
+	return &fakeIdempotencyRepo{keys: map[string]*model.IdempotencyKey{}}
+}
+
+func (r *fakeIdempotencyRepo) Acquire(_ context.Context, key *model.IdempotencyKey, now time.Time) (*model.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.keys[key.Key]; ok && !held.ExpiresAt.Before(now) &&
+		(held.StatusCode != 0 || !held.LockedUntil.Before(now)) {
+		copied := *held
+		return &copied, nil
+	}
+	copied := *key
+	r.keys[key.Key] = &copied
+	return nil, nil
+}
+
+func (r *fakeIdempotencyRepo) Complete(_ context.Context, key string, status int, header http.Header, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.keys[key]; ok && held.StatusCode == 0 {
+		held.StatusCode, held.Header, held.Body = status, header, body
+	}
+	return nil
+}
+
+func (r *fakeIdempotencyRepo) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.keys[key]; ok && held.StatusCode == 0 {
+		delete(r.keys, key)
+	}
+	return nil
+}
+
+func (r *fakeIdempotencyRepo) DeleteExpired(context.Context, time.Time) (int64, error) { return 0, nil }
+
+var testIdempotencyConfig = config.IdempotencyConfig{TTL: time.Hour, LockTimeout: time.Minute}
+
+// idempotencyEngine serves POST /users, counting the handler's runs.
+func idempotencyEngine(repo *fakeIdempotencyRepo, handler gin.HandlerFunc) *gin.Engine {
+	engine := newEngine(RequestID(), ErrorHandler(ErrorOptions{}), Idempotency(repo, testIdempotencyConfig))
+	engine.POST("/users", handler)
+	engine.PUT("/users", handler)
+	return engine
+}
+
+func postUser(key, body string, principal *auth.Principal) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if principal != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	return req
+}
+
+var alice = &auth.Principal{Kind: auth.KindUser, Subject: "1"}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	runs := 0
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		runs++
+		c.Header("Location", "/v1/users/7")
+		c.JSON(http.StatusCreated, gin.H{"id": 7, "run": runs})
+	})
+
+	first := do(engine, postUser("k1", `{"name":"a"}`, alice))
+	retry := do(engine, postUser("k1", `{"name":"a"}`, alice))
+
+	if runs != 1 {
+		t.Fatalf("handler ran %d times, want 1", runs)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	for h, want := range map[string]string{
+		"Location":               "/v1/users/7",
+		"Content-Type":           "application/json; charset=utf-8",
+		IdempotentReplayedHeader: "true",
+	} {
+		if got := retry.Header().Get(h); got != want {
+			t.Errorf("retry %s = %q, want %q", h, got, want)
+		}
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("first response marked as replayed")
+	}
+	// Outer middleware headers are fresh on every response, not replayed.
+	if got := retry.Header().Values(RequestIDHeader); len(got) != 1 || got[0] == first.Header().Get(RequestIDHeader) {
+		t.Errorf("retry %s = %q, want one new ID", RequestIDHeader, got)
+	}
+}
+
+func TestIdempotencyKeyReusedWithDifferentRequest(t *testing.T) {
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+	do(engine, postUser("k1", `{"name":"a"}`, alice))
+
+	w := do(engine, postUser("k1", `{"name":"b"}`, alice))
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if body := decodeErrorBody(t, w); body.Error.Code != e.CodeConflict {
+		t.Errorf("code = %q, want %q", body.Error.Code, e.CodeConflict)
+	}
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	firstDone := make(chan *httptest.ResponseRecorder)
+	go func() { firstDone <- do(engine, postUser("k1", `{}`, alice)) }()
+	<-started
+
+	w := do(engine, postUser("k1", `{}`, alice))
+	close(finish)
+	first := <-firstDone
+
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("duplicate = %d, Retry-After %q; want %d, 1", w.Code, w.Header().Get("Retry-After"), http.StatusConflict)
+	}
+	if first.Code != http.StatusCreated {
+		t.Errorf("first = %d, want %d", first.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyFailureIsNotStored(t *testing.T) {
+	runs := 0
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		runs++
+		if runs == 1 {
+			_ = c.Error(e.New(e.CodeInternal, "boom"))
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	first := do(engine, postUser("k1", `{}`, alice))
+	retry := do(engine, postUser("k1", `{}`, alice))
+
+	if first.Code != http.StatusInternalServerError || retry.Code != http.StatusCreated || runs != 2 {
+		t.Errorf("got %d then %d after %d runs, want 500 then 201 after 2", first.Code, retry.Code, runs)
+	}
+}
+
+func TestIdempotencyReleasesOnPanic(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	engine := idempotencyEngine(repo, func(c *gin.Context) { panic("boom") })
+
+	func() {
+		defer func() { _ = recover() }()
+		do(engine, postUser("k1", `{}`, alice))
+	}()
+
+	if len(repo.keys) != 0 {
+		t.Errorf("keys = %v, want the claim released", repo.keys)
+	}
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func() *http.Request
+	}{
+		{name: "no key", req: func() *http.Request { return postUser("", `{}`, alice) }},
+		{name: "PUT", req: func() *http.Request {
+			req := postUser("k1", `{}`, alice)
+			req.Method = http.MethodPut
+			return req
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeIdempotencyRepo()
+			runs := 0
+			engine := idempotencyEngine(repo, func(c *gin.Context) {
+				runs++
+				c.Status(http.StatusNoContent)
+			})
+
+			do(engine, tt.req())
+			do(engine, tt.req())
+
+			if runs != 2 || len(repo.keys) != 0 {
+				t.Errorf("runs = %d, keys = %d; want 2, 0", runs, len(repo.keys))
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysArePerCaller(t *testing.T) {
+	runs := 0
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		runs++
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	do(engine, postUser("k1", `{}`, alice))
+	do(engine, postUser("k1", `{}`, &auth.Principal{Kind: auth.KindUser, Subject: "2"}))
+
+	if runs != 2 {
+		t.Errorf("handler ran %d times, want 2", runs)
+	}
+}
+
+func TestIdempotencyInvalidKey(t *testing.T) {
+	engine := idempotencyEngine(newFakeIdempotencyRepo(), func(c *gin.Context) {
+		t.Error("handler ran")
+	})
+
+	for _, key := range []string{"has space", strings.Repeat("k", 256)} {
+		w := do(engine, postUser(key, `{}`, alice))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("key %q: status = %d, want %d", key, w.Code, http.StatusBadRequest)
+		}
+	}
+}
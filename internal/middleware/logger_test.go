@@ -40,9 +40,10 @@ func (r *logRecorder) Enabled(context.Context, slog.Level) bool { return true }
 
 func (r *logRecorder) Handle(ctx context.Context, rec slog.Record) error {
 	entry := logEntry{
-		Level:   rec.Level,
-		Message: rec.Message,
-		Attrs:   make(map[string]slog.Value, rec.NumAttrs()),
+		Level:     rec.Level,
+		Message:   rec.Message,
+		Attrs:     make(map[string]slog.Value, rec.NumAttrs()),
+		RequestID: RequestIDFrom(ctx),
 	}
 	rec.Attrs(func(a slog.Attr) bool {
 		entry.Attrs[a.Key] = a.Value
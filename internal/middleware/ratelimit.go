@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateKeyFunc names the budget a request spends from.
+type RateKeyFunc func(c *gin.Context) string
+
+// ByClientIP gives each client IP its own budget.
+func ByClientIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByCaller gives each authenticated user or API key its own budget, and falls
+// back to [ByClientIP] for anonymous requests. It must run after
+// [Authenticate] to see the caller.
+func ByCaller(c *gin.Context) string {
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok {
+		return string(p.Kind) + ":" + p.Subject
+	}
+	return ByClientIP(c)
+}
+
+// RateLimit aborts requests over limit with RATE_LIMITED. Routes sharing a
+// name share budgets. Every response carries the RateLimit-* headers; a store
+// failure is logged and lets the request through.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key RateKeyFunc) gin.HandlerFunc {
+	policy := strconv.Itoa(limit.Count) + ";w=" + strconv.Itoa(ceilSeconds(limit.Period))
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), name+":"+key(c), limit, time.Now())
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "rate limit unavailable", slog.String("limit", name), logger.Err(err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Count))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			_ = c.Error(e.New(e.CodeRateLimited, "rate limit exceeded").WithRetryAfter(res.RetryAfter))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
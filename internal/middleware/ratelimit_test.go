@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// failingStore is a store that cannot be reached.
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Count: 2, Period: time.Minute}
+	engine := newEngine(ErrorHandler(ErrorOptions{}), RateLimit(ratelimit.NewMemoryStore(), "test", limit, ByClientIP))
+	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+		req.RemoteAddr = ip + ":1234"
+		return do(engine, req)
+	}
+
+	for _, wantRemaining := range []string{"1", "0"} {
+		w := request("203.0.113.7")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		for header, want := range map[string]string{
+			"RateLimit-Policy":    "2;w=60",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": wantRemaining,
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+	}
+
+	w := request("203.0.113.7")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if body := decodeErrorBody(t, w); body.Error.Code != e.CodeRateLimited {
+		t.Errorf("code = %s, want %s", body.Error.Code, e.CodeRateLimited)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want %q", got, "30")
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want %q", got, "0")
+	}
+
+	if w := request("198.51.100.1"); w.Code != http.StatusOK {
+		t.Errorf("another client got status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	rec := captureLogs(t)
+	limit := ratelimit.Limit{Count: 1, Period: time.Minute}
+	engine := newEngine(ErrorHandler(ErrorOptions{}), RateLimit(failingStore{}, "test", limit, ByClientIP))
+	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if entries := rec.all(); len(entries) != 1 || entries[0].Message != "rate limit unavailable" {
+		t.Errorf("log records = %+v, want one rate limit error", entries)
+	}
+}
+
+func TestByCaller(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      string
+	}{
+		{name: "user", principal: &auth.Principal{Kind: auth.KindUser, Subject: "42"}, want: "user:42"},
+		{name: "api key", principal: &auth.Principal{Kind: auth.KindAPIKey, Subject: "k1"}, want: "api_key:k1"},
+		{name: "anonymous", want: "ip:203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "203.0.113.7:1234"
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = req
+
+			if got := ByCaller(c); got != tt.want {
+				t.Errorf("ByCaller() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds a client-supplied ID; a UUID is 36.
+const maxRequestIDLen = 128
+
+type requestIDKeyType struct{}
+
+var requestIDKey = requestIDKeyType{}
+
+// RequestID gives every request an ID: the client's X-Request-ID when it is
+// well formed, otherwise a new UUIDv7. The ID is stored on the request context
+// and echoed in the response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = util.NewID()
+		}
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// WithRequestID stores id in the context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom returns the ID stored in ctx, or "" when there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// LogRequestID stamps the request ID on log lines; register it with
+// [logger.Init].
+func LogRequestID() logger.ContextAttrFunc {
+	return func(ctx context.Context) []slog.Attr {
+		id := RequestIDFrom(ctx)
+		if id == "" {
+			return nil
+		}
+		return []slog.Attr{slog.String("request_id", id)}
+	}
+}
+
+// validRequestID accepts IDs that are safe to log and echo: printable ASCII
+// letters, digits and -_.: only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
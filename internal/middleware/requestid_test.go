@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "missing"},
+		{name: "uuid", header: "0198c3a4-5b6e-7c1d-8e2f-3a4b5c6d7e8f", wantKept: true},
+		{name: "other charset", header: "lb-1:req_42.a", wantKept: true},
+		{name: "longest", header: strings.Repeat("a", maxRequestIDLen), wantKept: true},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLen+1)},
+		{name: "space", header: "req 42"},
+		{name: "newline", header: "req\n42"},
+		{name: "non-ascii", header: "réq"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext string
+			engine := newEngine(RequestID())
+			engine.GET("/resource", func(c *gin.Context) {
+				inContext = RequestIDFrom(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := do(engine, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got != inContext {
+				t.Errorf("header %q differs from context %q", got, inContext)
+			}
+			if tt.wantKept {
+				if got != tt.header {
+					t.Errorf("request ID = %q, want the client's %q", got, tt.header)
+				}
+				return
+			}
+			if id, err := uuid.Parse(got); err != nil || id.Version() != 7 {
+				t.Errorf("request ID = %q, want a new UUIDv7", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDInLogsAndErrors(t *testing.T) {
+	rec := captureLogs(t)
+	engine := newEngine(RequestID(), ErrorHandler(ErrorOptions{}))
+	engine.GET("/resource", failWith(e.New(e.CodeNotFound, "user not found")))
+
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := do(engine, req)
+
+	if body := decodeErrorBody(t, w); body.Error.RequestID != "req-42" {
+		t.Errorf("envelope request_id = %q, want %q", body.Error.RequestID, "req-42")
+	}
+	if entry := rec.only(t); entry.RequestID != "req-42" {
+		t.Errorf("logged with request ID %q, want %q", entry.RequestID, "req-42")
+	}
+}
+
+func TestLogRequestID(t *testing.T) {
+	extract := LogRequestID()
+
+	if attrs := extract(context.Background()); attrs != nil {
+		t.Errorf("attrs without an ID = %v, want none", attrs)
+	}
+	attrs := extract(WithRequestID(context.Background(), "req-42"))
+	if len(attrs) != 1 || attrs[0].Key != "request_id" || attrs[0].Value.String() != "req-42" {
+		t.Errorf("attrs = %v, want request_id=req-42", attrs)
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	"github.com/gin-gonic/gin"
+)
+
+var testServerConfig = config.ServerConfig{
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	HSTSIncludeSubdomains: true,
+	ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	ReferrerPolicy:        "no-referrer",
+	FrameOptions:          "DENY",
+	PermissionsPolicy:     "camera=()",
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	engine := newEngine(SecurityHeaders(testServerConfig))
+	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	assertHeaders(t, w, map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "no-referrer",
+		"X-Frame-Options":           "DENY",
+		"Permissions-Policy":        "camera=()",
+		"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+	})
+}
+
+func TestSecurityHeadersOff(t *testing.T) {
+	engine := newEngine(SecurityHeaders(config.ServerConfig{
+		HSTSPreload:           true, // no max-age, so no HSTS at all
+		ContentSecurityPolicy: "off",
+		ReferrerPolicy:        "off",
+	}))
+	engine.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	for _, h := range []string{
+		"Strict-Transport-Security", "Referrer-Policy", "X-Frame-Options",
+		"Permissions-Policy", "Content-Security-Policy",
+	} {
+		if got := w.Header().Get(h); got != "" {
+			t.Errorf("%s = %q, want none", h, got)
+		}
+	}
+	// Always on: there is no reason to let browsers sniff.
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+}
+
+func TestHSTSPreload(t *testing.T) {
+	cfg := testServerConfig
+	cfg.HSTSPreload = true
+	engine := newEngine(SecurityHeaders(cfg))
+	engine.GET("/resource", func(c *gin.Context) {})
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains; preload" {
+		t.Errorf("Strict-Transport-Security = %q", got)
+	}
+}
+
+func TestCSPNonce(t *testing.T) {
+	cfg := testServerConfig
+	cfg.ContentSecurityPolicy = "script-src 'nonce-{nonce}'; style-src 'nonce-{nonce}'"
+	var nonces []string
+	engine := newEngine(SecurityHeaders(cfg))
+	engine.GET("/page", func(c *gin.Context) { nonces = append(nonces, CSPNonce(c)) })
+
+	first := do(engine, httptest.NewRequest(http.MethodGet, "/page", nil))
+	second := do(engine, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if len(nonces) != 2 || len(nonces[0]) != 24 || nonces[0] == nonces[1] {
+		t.Fatalf("nonces = %q, want two different 16-byte nonces", nonces)
+	}
+	want := "script-src 'nonce-" + nonces[0] + "'; style-src 'nonce-" + nonces[0] + "'"
+	if got := first.Header().Get("Content-Security-Policy"); got != want {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, want)
+	}
+	if got := second.Header().Get("Content-Security-Policy"); !strings.Contains(got, nonces[1]) {
+		t.Errorf("second policy %q does not carry its own nonce", got)
+	}
+}
+
+// A route serving a page swaps in its own policy.
+func TestCSPRouteOverride(t *testing.T) {
+	var nonce string
+	engine := newEngine(SecurityHeaders(testServerConfig))
+	engine.GET("/docs", CSP("default-src 'self'; script-src 'self' 'nonce-{nonce}'"), func(c *gin.Context) {
+		nonce = CSPNonce(c)
+	})
+	engine.GET("/bare", CSP("off"), func(c *gin.Context) {})
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if got, want := w.Header().Get("Content-Security-Policy"), "default-src 'self'; script-src 'self' 'nonce-"+nonce+"'"; got != want {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, want)
+	}
+
+	w = do(engine, httptest.NewRequest(http.MethodGet, "/bare", nil))
+	if got := w.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("Content-Security-Policy = %q, want none", got)
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestContextKey keeps the request context from before any Timeout, whose
+// cancellation means the client went away.
+const requestContextKey = "middleware.requestContext"
+
+// Timeout gives the request a deadline d from now. Work that honours the
+// request context, GORM queries included, then fails with
+// context.DeadlineExceeded, which ErrorHandler answers with TIMEOUT. When
+// Timeouts are nested, as with a route overriding its group's, the innermost
+// one decides; d <= 0 there removes the deadline.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var stop func() bool
+		if v, nested := c.Get(requestContextKey); nested {
+			// Drop the outer deadline but keep the values set since, and
+			// still give up when the client does.
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			defer cancel()
+			stop = context.AfterFunc(v.(context.Context), cancel)
+		} else {
+			c.Set(requestContextKey, ctx)
+		}
+		if stop != nil {
+			defer stop()
+		}
+		if d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// The handler ran out of time but produced no error and no response.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && len(c.Errors) == 0 && !c.Writer.Written() {
+			_ = c.Error(ctx.Err())
+		}
+	}
+}
+
+// RouteTimeouts gives the routes in timeouts their own deadline, nested inside
+// an earlier Timeout. A key is a route path as registered, such as
+// "/v1/users/:userID/mfa", or the prefix of a group of them, such as
+// "/v1/auth"; the longest one matching a route wins, and routes none match
+// keep the outer deadline.
+func RouteTimeouts(timeouts map[string]time.Duration) gin.HandlerFunc {
+	if len(timeouts) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	var (
+		mu      sync.RWMutex
+		byRoute = make(map[string]gin.HandlerFunc) // nil: no override
+	)
+	lookup := func(route string) gin.HandlerFunc {
+		mu.RLock()
+		h, ok := byRoute[route]
+		mu.RUnlock()
+		if ok {
+			return h
+		}
+		if prefix := matchRoute(timeouts, route); prefix != "" {
+			h = Timeout(timeouts[prefix])
+		}
+		mu.Lock()
+		byRoute[route] = h
+		mu.Unlock()
+		return h
+	}
+
+	return func(c *gin.Context) {
+		if h := lookup(c.FullPath()); h != nil {
+			h(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// matchRoute returns the longest key of timeouts that route is under, or ""
+// when it is under none.
+func matchRoute(timeouts map[string]time.Duration, route string) string {
+	best := ""
+	for prefix := range timeouts {
+		if UnderRoute(route, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return best
+}
+
+// UnderRoute reports whether route is prefix, or a route below it: "/v1/auth"
+// covers "/v1/auth/login" but not "/v1/authz".
+func UnderRoute(route, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return route == prefix || strings.HasPrefix(route, prefix+"/")
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// waitForContext is a handler stuck on slow work that honours its context.
+func waitForContext(c *gin.Context) {
+	<-c.Request.Context().Done()
+	_ = c.Error(c.Request.Context().Err())
+}
+
+func TestTimeout(t *testing.T) {
+	captureLogs(t)
+	engine := newEngine(ErrorHandler(ErrorOptions{}), Timeout(10*time.Millisecond))
+	engine.GET("/slow", waitForContext)
+	// Swallows the error and writes nothing.
+	engine.GET("/silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	for _, path := range []string{"/slow", "/silent"} {
+		t.Run(path, func(t *testing.T) {
+			start := time.Now()
+			w := do(engine, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if took := time.Since(start); took > time.Second {
+				t.Errorf("request took %v, want it cut off near the deadline", took)
+			}
+			if w.Code != http.StatusGatewayTimeout {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+			}
+			if body := decodeErrorBody(t, w); body.Error.Code != e.CodeTimeout {
+				t.Errorf("code = %s, want %s", body.Error.Code, e.CodeTimeout)
+			}
+		})
+	}
+}
+
+func TestTimeoutLeavesFastRequestsAlone(t *testing.T) {
+	var deadline time.Time
+	engine := newEngine(ErrorHandler(ErrorOptions{}), Timeout(time.Minute))
+	engine.GET("/resource", func(c *gin.Context) {
+		deadline, _ = c.Request.Context().Deadline()
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if until := time.Until(deadline); until <= 50*time.Second || until > time.Minute {
+		t.Errorf("deadline in %v, want about a minute", until)
+	}
+}
+
+type testKeyType struct{}
+
+// A route's own Timeout replaces its group's, keeping what was put on the
+// context in between.
+func TestTimeoutNested(t *testing.T) {
+	tests := []struct {
+		name         string
+		inner        time.Duration
+		wantDeadline bool
+	}{
+		{name: "longer", inner: time.Hour, wantDeadline: true},
+		{name: "none", inner: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx context.Context
+			engine := newEngine(ErrorHandler(ErrorOptions{}), Timeout(time.Second), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), testKeyType{}, "kept"))
+			})
+			engine.GET("/resource", Timeout(tt.inner), func(c *gin.Context) { ctx = c.Request.Context() })
+
+			do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+			deadline, ok := ctx.Deadline()
+			if ok != tt.wantDeadline || (ok && time.Until(deadline) < 50*time.Minute) {
+				t.Errorf("deadline = %v, %v; want the inner timeout's", deadline, ok)
+			}
+			if got := ctx.Value(testKeyType{}); got != "kept" {
+				t.Errorf("context value = %v, want it kept", got)
+			}
+		})
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	timeouts := map[string]time.Duration{
+		"/auth":              time.Hour,
+		"/auth/slow":         2 * time.Hour,
+		"/users/:userID/mfa": 3 * time.Hour,
+	}
+	tests := []struct {
+		route string
+		want  time.Duration
+	}{
+		{route: "/auth/login", want: time.Hour},
+		{route: "/auth/slow", want: 2 * time.Hour},
+		{route: "/users/:userID/mfa", want: 3 * time.Hour},
+		{route: "/authz", want: time.Minute},
+		{route: "/users/:userID", want: time.Minute},
+	}
+
+	var deadline time.Time
+	engine := newEngine(ErrorHandler(ErrorOptions{}), Timeout(time.Minute), RouteTimeouts(timeouts))
+	for _, tt := range tests {
+		engine.GET(tt.route, func(c *gin.Context) { deadline, _ = c.Request.Context().Deadline() })
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			do(engine, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(tt.route, ":userID", "1"), nil))
+			if until := time.Until(deadline); until > tt.want || until < tt.want-10*time.Second {
+				t.Errorf("deadline in %v, want about %v", until, tt.want)
+			}
+		})
+	}
+}
+
+// The client going away still cancels work under a nested Timeout.
+func TestTimeoutNestedFollowsClient(t *testing.T) {
+	clientCtx, disconnect := context.WithCancel(context.Background())
+	var err error
+	engine := newEngine(Timeout(time.Hour))
+	engine.GET("/resource", Timeout(time.Hour), func(c *gin.Context) {
+		disconnect()
+		<-c.Request.Context().Done()
+		err = c.Request.Context().Err()
+	})
+
+	do(engine, httptest.NewRequest(http.MethodGet, "/resource", nil).WithContext(clientCtx))
+
+	if err != context.Canceled {
+		t.Errorf("context error = %v, want %v", err, context.Canceled)
+	}
+}
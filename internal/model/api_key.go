@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type APIKey struct {
+	ID         uint64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name       string     `json:"name" gorm:"column:name;not null"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;uniqueIndex;not null"`
+	Hash       string     `json:"-" gorm:"column:hash;not null"`
+	Scopes     []string   `json:"scopes" gorm:"column:scopes;serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (APIKey) TableName() string { return "api_keys" }
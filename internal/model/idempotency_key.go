@@ -0,0 +1,22 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyKey holds the response to a request sent with an Idempotency-Key
+// header, so a retry of the request gets the same response. StatusCode is 0
+// while the first request is still being handled.
+type IdempotencyKey struct {
+	Key         string      `gorm:"column:key;primaryKey"`       // "<caller>:<key>", so callers cannot see each other's responses
+	Fingerprint string      `gorm:"column:fingerprint;not null"` // sha256 of the method, URI and body
+	StatusCode  int         `gorm:"column:status_code;not null"`
+	Header      http.Header `gorm:"column:header;serializer:json"` // only what the handler set
+	Body        []byte      `gorm:"column:body"`
+	LockedUntil time.Time   `gorm:"column:locked_until;not null"` // an unfinished key may be taken over after this
+	ExpiresAt   time.Time   `gorm:"column:expires_at;not null"`
+	CreatedAt   time.Time   `gorm:"column:created_at"`
+}
+
+func (IdempotencyKey) TableName() string { return "idempotency_keys" }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// LoginAttempt counts recent failed logins under one key: an account
+// ("email:<address>") or a client ("ip:<address>").
+type LoginAttempt struct {
+	Key           string     `gorm:"column:key;primaryKey"`
+	Failures      int        `gorm:"column:failures;not null"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at;not null"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+}
+
+func (LoginAttempt) TableName() string { return "login_attempts" }
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// authenticator is lost.
+type RecoveryCode struct {
+	ID        uint64     `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    uint64     `gorm:"column:user_id;index;not null"`
+	Hash      string     `gorm:"column:hash;not null"` // sha256 of the normalized code
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (RecoveryCode) TableName() string { return "mfa_recovery_codes" }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RefreshToken is one link in a rotation chain. Every token minted from the
+// same login shares a FamilyID, so a replayed token can take down the chain.
+type RefreshToken struct {
+	ID        uint64     `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    uint64     `gorm:"column:user_id;index;not null"`
+	FamilyID  string     `gorm:"column:family_id;index;not null"`
+	Hash      string     `gorm:"column:hash;uniqueIndex;not null"` // sha256 of the token; the token itself is never stored
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`    // set when exchanged for a successor
+	RevokedAt *time.Time `gorm:"column:revoked_at"` // set on logout or reuse
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (RefreshToken) TableName() string { return "refresh_tokens" }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Role is a named set of permissions, e.g. "admin" → ["*"].
+type Role struct {
+	ID          uint64    `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"column:name;uniqueIndex;not null"`
+	Permissions []string  `json:"permissions" gorm:"column:permissions;serializer:json"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// UserRole assigns a role to a user.
+type UserRole struct {
+	UserID uint64 `gorm:"column:user_id;primaryKey"`
+	RoleID uint64 `gorm:"column:role_id;primaryKey"`
+}
@@ -7,12 +7,17 @@ import (
 )
 
 type User struct {
-	ID        uint64         `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
-	Name      string         `json:"name" gorm:"column:name;not null" validate:"required"`
-	Email     *string        `json:"email" gorm:"column:email;uniqueIndex" validate:"omitzero,email"`
-	CreatedAt time.Time      `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"column:updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	ID              uint64         `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name            string         `json:"name" gorm:"column:name;not null" validate:"required"`
+	Email           *string        `json:"email" gorm:"column:email;uniqueIndex" validate:"omitzero,email"`
+	PasswordHash    *string        `json:"-" gorm:"column:password_hash"` // argon2id PHC string; nil means no login
+	EmailVerifiedAt *time.Time     `json:"email_verified_at" gorm:"column:email_verified_at"`
+	MFASecret       *string        `json:"-" gorm:"column:mfa_secret"`                       // sealed TOTP key, set once enrollment starts
+	MFAEnabledAt    *time.Time     `json:"mfa_enabled_at" gorm:"column:mfa_enabled_at"`      // nil until enrollment is confirmed
+	MFALastStep     int64          `json:"-" gorm:"column:mfa_last_step;not null;default:0"` // last TOTP step accepted
+	CreatedAt       time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
 }
 
 type UserListFilter struct {
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// TokenPurpose says what a [UserToken] may be exchanged for.
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+	TokenMFAChallenge  TokenPurpose = "mfa_challenge"
+)
+
+// UserToken is a single-use secret handed to a user, e.g. an email
+// verification link or the challenge a login with MFA has to answer.
+type UserToken struct {
+	ID        uint64       `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    uint64       `gorm:"column:user_id;index;not null"`
+	Purpose   TokenPurpose `gorm:"column:purpose;not null"`
+	Hash      string       `gorm:"column:hash;uniqueIndex;not null"` // sha256 of the token
+	Email     string       `gorm:"column:email;not null"`            // address the token was sent to, or the user's at the time
+	ExpiresAt time.Time    `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time   `gorm:"column:used_at"`
+	CreatedAt time.Time    `gorm:"column:created_at"`
+}
+
+func (UserToken) TableName() string { return "user_tokens" }
@@ -1,8 +1,8 @@
 package pagination
 
 type Pagination struct {
-	Page     int   `json:"page" form:"page"`
-	PageSize int   `json:"page_size" form:"page_size"`
+	Page     int   `json:"page" query:"page"`
+	PageSize int   `json:"page_size" query:"page_size"`
 	Total    int64 `json:"total" binding:"-"`
 }
 
@@ -0,0 +1,204 @@
+package params
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// strictKey marks a request whose bodies may not have fields their request
+// struct lacks.
+const strictKey = "params.strictJSON"
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// BindJSON decodes the request's JSON body into dst, reporting a body that
+// does not decode as INVALID_INPUT: empty, malformed or truncated JSON, more
+// than one value, a value of the wrong type, with a violation naming the
+// field and the type expected, and, on routes behind [StrictJSON], a field
+// dst does not have. Other errors, such as a body over the size limit, are
+// returned as they are. Validating dst is left to the caller.
+func BindJSON(c *gin.Context, dst any) error {
+	var body []byte
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			return err
+		}
+	}
+
+	strict := c.GetBool(strictKey)
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(dst)
+	if err == nil {
+		// A body is one value; anything after it would be silently dropped.
+		end := dec.InputOffset()
+		if dec.Decode(new(json.RawMessage)) != io.EOF {
+			return e.New(e.CodeInvalidInput, "malformed JSON").
+				WithDetails(map[string]string{"offset": strconv.FormatInt(end, 10)})
+		}
+		return nil
+	}
+	if appErr := decodeError(err); appErr != nil {
+		return appErr
+	}
+	// encoding/json's unknown field error has no type to tell it by, so look
+	// for the field instead.
+	if strict {
+		if path, ok := unknownField(body, reflect.TypeOf(dst), ""); ok {
+			return e.Wrap(err, e.CodeInvalidInput, "invalid request body").WithViolations([]e.Violation{{
+				Path:    path,
+				Rule:    "unknown",
+				Message: "is not a known field",
+			}})
+		}
+	}
+	return err
+}
+
+// StrictJSON makes [BindJSON] reject bodies with fields their request struct
+// does not have, on the routes it is used on. The setting lives on each
+// request, so two routers in one process can differ.
+func StrictJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(strictKey, true)
+		c.Next()
+	}
+}
+
+// decodeError classifies an error from encoding/json, or returns nil. Syntax
+// and type errors are [e.From]'s, which classifies them however a body was
+// bound; the rest only mean a bad body here.
+func decodeError(err error) *e.AppError {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		timeErr   *time.ParseError
+	)
+	switch {
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return e.From(err)
+	case errors.Is(err, io.EOF):
+		return e.Wrap(err, e.CodeInvalidInput, "request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return e.Wrap(err, e.CodeInvalidInput, "malformed JSON")
+	case errors.As(err, &timeErr):
+		// time.Time does not say which field it was decoding.
+		return e.Wrap(err, e.CodeInvalidInput, "timestamps must be RFC 3339")
+	}
+	return nil
+}
+
+// unknownField finds a member of the JSON in data that t has no field for,
+// matching names as encoding/json does: exactly, or else ignoring case. It
+// returns the member's JSON pointer below path, and whether there was one.
+// Types that decode themselves take any members.
+func unknownField(data []byte, t reflect.Type, path string) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return "", false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(data, &members) != nil {
+			return "", false
+		}
+		fields := jsonFields(t)
+		for _, name := range slices.Sorted(maps.Keys(members)) {
+			field, ok := fields[name]
+			for key, f := range fields {
+				if !ok && strings.EqualFold(key, name) {
+					field, ok = f, true
+				}
+			}
+			member := path + "/" + escapePointer(name)
+			if !ok {
+				return member, true
+			}
+			if p, ok := unknownField(members[name], field, member); ok {
+				return p, true
+			}
+		}
+	case reflect.Map:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(data, &members) != nil {
+			return "", false
+		}
+		for _, name := range slices.Sorted(maps.Keys(members)) {
+			if p, ok := unknownField(members[name], t.Elem(), path+"/"+escapePointer(name)); ok {
+				return p, true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return "", false
+		}
+		for i, item := range items {
+			if p, ok := unknownField(item, t.Elem(), path+"/"+strconv.Itoa(i)); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// jsonFields maps the JSON names of struct t's fields, those of embedded
+// structs included, to their types. A field of t hides an embedded one of the
+// same name.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	promoted := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				maps.Copy(promoted, jsonFields(embedded))
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	for name, typ := range promoted {
+		if _, ok := fields[name]; !ok {
+			fields[name] = typ
+		}
+	}
+	return fields
+}
+
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
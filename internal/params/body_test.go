@@ -0,0 +1,156 @@
+package params
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+type address struct {
+	Zip string `json:"zip"`
+}
+
+type bodyRequest struct {
+	Name      string         `json:"name"`
+	Age       uint           `json:"age"`
+	Admin     bool           `json:"admin"`
+	Score     *float64       `json:"score"`
+	Addresses []address      `json:"addresses"`
+	Labels    map[string]int `json:"labels"`
+	Since     time.Time      `json:"since"`
+}
+
+// bindJSON runs BindJSON on a POST with body, behind middleware.
+func bindJSON(t *testing.T, body string, dst any, middleware ...gin.HandlerFunc) error {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var err error
+	engine := gin.New()
+	engine.Use(middleware...)
+	engine.POST("/", func(c *gin.Context) {
+		err = BindJSON(c, dst)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	return err
+}
+
+func TestBindJSON(t *testing.T) {
+	var got bodyRequest
+	if err := bindJSON(t, `{"name":"Ada","age":36,"addresses":[{"zip":"N1"}]}`+"\n", &got); err != nil {
+		t.Fatalf("BindJSON() error = %v", err)
+	}
+	want := bodyRequest{Name: "Ada", Age: 36, Addresses: []address{{Zip: "N1"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BindJSON() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBindJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantDetails map[string]string
+		want        []e.Violation
+	}{
+		{name: "empty", body: "", wantMessage: "request body is empty"},
+		{name: "truncated", body: `{"name":"Ada"`, wantMessage: "malformed JSON"},
+		{name: "syntax", body: `{"name" "Ada"}`, wantMessage: "malformed JSON", wantDetails: map[string]string{"offset": "9"}},
+		{name: "second value", body: `{"name":"Ada"} {"admin":true}`, wantMessage: "malformed JSON", wantDetails: map[string]string{"offset": "14"}},
+		{name: "trailing garbage", body: `{"name":"Ada"}garbage`, wantMessage: "malformed JSON", wantDetails: map[string]string{"offset": "14"}},
+		{name: "string", body: `{"name":5}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/name", Rule: "type", Param: "string", Message: "must be a string"},
+		}},
+		{name: "negative", body: `{"age":-1}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/age", Rule: "type", Param: "integer", Message: "must be a whole number of 0 or more"},
+		}},
+		{name: "boolean", body: `{"admin":"yes"}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/admin", Rule: "type", Param: "boolean", Message: "must be true or false"},
+		}},
+		{name: "pointer", body: `{"score":"high"}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/score", Rule: "type", Param: "number", Message: "must be a number"},
+		}},
+		{name: "nested", body: `{"addresses":[{"zip":1}]}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/addresses/0/zip", Rule: "type", Param: "string", Message: "must be a string"},
+		}},
+		{name: "array", body: `{"addresses":{}}`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "/addresses", Rule: "type", Param: "array", Message: "must be an array"},
+		}},
+		{name: "body", body: `[1]`, wantMessage: "invalid request body", want: []e.Violation{
+			{Path: "", Rule: "type", Param: "object", Message: "must be an object"},
+		}},
+		{name: "timestamp", body: `{"since":"yesterday"}`, wantMessage: "timestamps must be RFC 3339"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bodyRequest
+			err := bindJSON(t, tt.body, &got)
+
+			var appErr *e.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("BindJSON() error = %v, want an *AppError", err)
+			}
+			if appErr.Code != e.CodeInvalidInput || appErr.Message != tt.wantMessage {
+				t.Errorf("error = %s %q, want %s %q", appErr.Code, appErr.Message, e.CodeInvalidInput, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(appErr.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", appErr.Details, tt.wantDetails)
+			}
+			if !reflect.DeepEqual(appErr.Violations, tt.want) {
+				t.Errorf("violations = %+v\nwant %+v", appErr.Violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindJSONUnknownFields(t *testing.T) {
+	body := `{"name":"Ada","role":"admin"}`
+
+	var lenient bodyRequest
+	if err := bindJSON(t, body, &lenient); err != nil {
+		t.Fatalf("BindJSON() error = %v, want unknown fields ignored", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		path string
+	}{
+		{name: "top level", body: body, path: "/role"},
+		{name: "nested", body: `{"addresses":[{"zip":"N1"},{"zip":"N2","city":"London"}]}`, path: "/addresses/1/city"},
+		{name: "escaped", body: `{"a/b":1}`, path: "/a~1b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var strict bodyRequest
+			err := bindJSON(t, tt.body, &strict, StrictJSON())
+			var appErr *e.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("BindJSON() error = %v, want an *AppError", err)
+			}
+			want := []e.Violation{{Path: tt.path, Rule: "unknown", Message: "is not a known field"}}
+			if appErr.Code != e.CodeInvalidInput || !reflect.DeepEqual(appErr.Violations, want) {
+				t.Errorf("error = %s %+v, want %s %+v", appErr.Code, appErr.Violations, e.CodeInvalidInput, want)
+			}
+		})
+	}
+
+	var trailing bodyRequest
+	if err := bindJSON(t, `{"name":"Ada"} {"role":"admin"}`, &trailing, StrictJSON()); err == nil {
+		t.Error("BindJSON() error = nil, want a second value smuggling an unknown field rejected")
+	}
+
+	var cased bodyRequest
+	if err := bindJSON(t, `{"NAME":"Ada"}`, &cased, StrictJSON()); err != nil {
+		t.Errorf("BindJSON() error = %v, want names matched ignoring case", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package params binds path, query and header parameters, and JSON bodies,
+// into a struct and validates it, so handlers get typed values and clients get
+// INVALID_INPUT for a malformed one instead of an internal error.
+//
+// Fields name their parameter with a uri, query or header tag:
+//
+//	type listRequest struct {
+//		UserID uint64   `uri:"userID"`
+//		Tags   []string `query:"tag" validate:"dive,required"`
+//		Locale string   `header:"Accept-Language"`
+//	}
+//
+// Supported types are strings, bools, integers, floats, [time.Duration] (as
+// "1h30m"), [time.Time] (RFC 3339), pointers to them, set only when the
+// parameter is present, and slices of them for repeated query parameters.
+// Embedded structs are bound field by field.
+//
+// [BindJSON] decodes a body by its json tags, leaving validation to the caller.
+package params
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	timeType     = reflect.TypeFor[time.Time]()
+)
+
+// source is where a tag's parameters come from.
+type source struct {
+	tag    string
+	lookup func(c *gin.Context, name string) ([]string, bool)
+}
+
+var sources = []source{
+	{tag: "uri", lookup: func(c *gin.Context, name string) ([]string, bool) {
+		v, ok := c.Params.Get(name)
+		return []string{v}, ok
+	}},
+	{tag: "query", lookup: func(c *gin.Context, name string) ([]string, bool) {
+		return c.GetQueryArray(name)
+	}},
+	{tag: "header", lookup: func(c *gin.Context, name string) ([]string, bool) {
+		v := c.Request.Header.Values(name)
+		return v, len(v) > 0
+	}},
+}
+
+// typeError is a parameter that does not parse as its field's type.
+type typeError struct {
+	want    string // kind of value expected, e.g. "integer"
+	message string
+}
+
+func (err *typeError) Error() string { return err.message }
+
+// Bind fills dst, a pointer to a struct, from the request's parameters, then
+// validates it against its validate tags. A parameter that does not parse
+// fails with INVALID_INPUT and a violation per parameter, before validation
+// runs; a failed rule fails as [validation.ValidateStruct] does.
+func Bind(c *gin.Context, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("params: Bind needs a pointer to a struct, got %T", dst)
+	}
+	var violations []e.Violation
+	if err := bindStruct(c, v.Elem(), &violations); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return e.New(e.CodeInvalidInput, "invalid parameters").WithViolations(violations)
+	}
+	return validation.ValidateStruct(dst)
+}
+
+func bindStruct(c *gin.Context, v reflect.Value, violations *[]e.Violation) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindStruct(c, v.Field(i), violations); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		for _, src := range sources {
+			name := field.Tag.Get(src.tag)
+			if name == "" || name == "-" {
+				continue
+			}
+			values, ok := src.lookup(c, name)
+			if !ok {
+				break
+			}
+			err := set(v.Field(i), values)
+			var te *typeError
+			if errors.As(err, &te) {
+				*violations = append(*violations, e.Violation{
+					Path:    "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(name),
+					Rule:    "type",
+					Param:   te.want,
+					Message: te.message,
+				})
+			} else if err != nil {
+				return fmt.Errorf("params: field %s: %w", field.Name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// set parses values into v: all of them into a slice, the first otherwise.
+func set(v reflect.Value, values []string) error {
+	switch {
+	case v.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, raw := range values {
+			if err := setScalar(s.Index(i), raw); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case v.Kind() == reflect.Pointer:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setScalar(ptr.Elem(), values[0]); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+	return setScalar(v, values[0])
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return &typeError{want: "duration", message: "must be a duration such as 1h30m"}
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return &typeError{want: "timestamp", message: "must be an RFC 3339 timestamp"}
+		}
+		v.Set(reflect.ValueOf(ts))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return &typeError{want: "boolean", message: "must be true or false"}
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "integer", "must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "integer", "must be a whole number of 0 or more")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "number", "must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func numberError(err error, want, message string) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return &typeError{want: want, message: "is out of range"}
+	}
+	return &typeError{want: want, message: message}
+}
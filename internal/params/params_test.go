@@ -0,0 +1,132 @@
+package params
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type embedded struct {
+	Page int `query:"page"`
+}
+
+type request struct {
+	UserID  uint64         `uri:"userID"`
+	Name    string         `query:"name"`
+	Tags    []string       `query:"tag"`
+	Limit   *int           `query:"limit"`
+	Active  bool           `query:"active"`
+	Ratio   float64        `query:"ratio"`
+	Since   time.Time      `query:"since"`
+	Within  time.Duration  `query:"within"`
+	Locale  string         `header:"Accept-Language"`
+	Missing *int           `query:"missing"`
+	Skipped string         `query:"-"`
+	Kind    string         `query:"kind" validate:"omitempty,oneof=a b"`
+	Extra   map[string]int `json:"-"`
+	embedded
+}
+
+// bind runs Bind on a request to target, routed like the API's /users/:userID.
+func bind(t *testing.T, target string, header http.Header, dst any) error {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var err error
+	engine := gin.New()
+	engine.GET("/users/:userID", func(c *gin.Context) {
+		err = Bind(c, dst)
+	})
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	return err
+}
+
+func TestBind(t *testing.T) {
+	var got request
+	err := bind(t,
+		"/users/42?name=+Ada+&tag=a&tag=b&limit=5&active=true&ratio=0.5&since=2026-01-02T03:04:05Z&within=1h30m&kind=a&page=3",
+		http.Header{"Accept-Language": {"es"}}, &got)
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	limit := 5
+	want := request{
+		UserID: 42,
+		Name:   "Ada",
+		Tags:   []string{"a", "b"},
+		Limit:  &limit,
+		Active: true,
+		Ratio:  0.5,
+		Since:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Within: 90 * time.Minute,
+		Locale: "es",
+		Kind:   "a",
+	}
+	want.Page = 3
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bind() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBindTypeErrors(t *testing.T) {
+	var got request
+	err := bind(t, "/users/-1?limit=many&active=maybe&ratio=x&since=yesterday&within=soon&page=99999999999999999999", nil, &got)
+
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Bind() error = %v, want an *AppError", err)
+	}
+	if appErr.Code != e.CodeInvalidInput {
+		t.Errorf("code = %s, want %s", appErr.Code, e.CodeInvalidInput)
+	}
+	want := []e.Violation{
+		{Path: "/userID", Rule: "type", Param: "integer", Message: "must be a whole number of 0 or more"},
+		{Path: "/limit", Rule: "type", Param: "integer", Message: "must be a whole number"},
+		{Path: "/active", Rule: "type", Param: "boolean", Message: "must be true or false"},
+		{Path: "/ratio", Rule: "type", Param: "number", Message: "must be a number"},
+		{Path: "/since", Rule: "type", Param: "timestamp", Message: "must be an RFC 3339 timestamp"},
+		{Path: "/within", Rule: "type", Param: "duration", Message: "must be a duration such as 1h30m"},
+		{Path: "/page", Rule: "type", Param: "integer", Message: "is out of range"},
+	}
+	if !reflect.DeepEqual(appErr.Violations, want) {
+		t.Errorf("violations = %+v\nwant %+v", appErr.Violations, want)
+	}
+}
+
+func TestBindValidates(t *testing.T) {
+	var got request
+	err := bind(t, "/users/1?kind=c", nil, &got)
+
+	var ve validator.ValidationErrors
+	if !errors.As(err, &ve) {
+		t.Fatalf("Bind() error = %v, want validation errors", err)
+	}
+	if v := e.From(err).Violations; len(v) != 1 || v[0].Path != "/kind" || v[0].Rule != "oneof" {
+		t.Errorf("violations = %+v, want /kind breaking oneof", v)
+	}
+}
+
+func TestBindRejectsBadTargets(t *testing.T) {
+	var notStruct int
+	var unsupported struct {
+		Ch chan int `query:"ch"`
+	}
+	for name, dst := range map[string]any{"not a pointer": request{}, "not a struct": &notStruct, "unsupported": &unsupported} {
+		err := bind(t, "/users/1?ch=1", nil, dst)
+		var appErr *e.AppError
+		if err == nil || errors.As(err, &appErr) {
+			t.Errorf("%s: Bind() error = %v, want a plain error", name, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const (
+	memoryShards = 64
+	// sweepEvery is how often a shard drops keys whose budgets are full again.
+	sweepEvery = time.Minute
+)
+
+// MemoryStore keeps budgets in the process. Each server counts on its own, so
+// behind a load balancer a caller gets the limit once per instance.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryShards]memoryShard
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	nextSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].tats = map[string]time.Time{}
+	}
+	return s
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	shard := &s.shards[maphash.String(s.seed, key)%memoryShards]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if !now.Before(shard.nextSweep) {
+		shard.sweep(now)
+	}
+	tat, res := limit.Take(shard.tats[key], now)
+	shard.tats[key] = tat
+	return res, nil
+}
+
+// sweep forgets keys with a full budget, which behave as if never seen.
+func (sh *memoryShard) sweep(now time.Time) {
+	for key, tat := range sh.tats {
+		if !tat.After(now) {
+			delete(sh.tats, key)
+		}
+	}
+	sh.nextSweep = now.Add(sweepEvery)
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Count: 1, Period: time.Minute}
+	now := time.Now()
+
+	if res, _ := s.Take(context.Background(), "a", limit, now); !res.Allowed {
+		t.Fatal("first request for a was denied")
+	}
+	if res, _ := s.Take(context.Background(), "a", limit, now); res.Allowed {
+		t.Error("second request for a was allowed")
+	}
+	if res, _ := s.Take(context.Background(), "b", limit, now); !res.Allowed {
+		t.Error("b was limited by a's requests")
+	}
+}
+
+func TestMemoryStoreConcurrentTakes(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Count: 50, Period: time.Hour}
+	now := time.Now()
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 200 {
+		wg.Go(func() {
+			if res, _ := s.Take(context.Background(), "k", limit, now); res.Allowed {
+				allowed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 50 {
+		t.Errorf("allowed %d requests, want 50", got)
+	}
+}
+
+func TestMemoryStoreSweepsFullBudgets(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Count: 1, Period: time.Second}
+	now := time.Now()
+
+	_, _ = s.Take(context.Background(), "idle", limit, now)
+	shard := &s.shards[0]
+	for i := range s.shards {
+		if _, ok := s.shards[i].tats["idle"]; ok {
+			shard = &s.shards[i]
+		}
+	}
+
+	shard.mu.Lock()
+	shard.sweep(now.Add(2 * time.Second))
+	_, kept := shard.tats["idle"]
+	shard.mu.Unlock()
+	if kept {
+		t.Error("sweep kept a key whose budget is full")
+	}
+}
@@ -0,0 +1,82 @@
+// Package ratelimit decides whether a request fits in its caller's budget. It
+// implements GCRA (the generic cell rate algorithm), which behaves like a token
+// bucket but keeps a single timestamp per key. Where that timestamp lives is up
+// to a [Store].
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Count requests per Period. A full budget can be spent at once;
+// it then refills evenly over the period.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseLimit reads a limit written as "<count>/<period>", e.g. "10/1m" or
+// "600/1h".
+func ParseLimit(s string) (Limit, error) {
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q is not <count>/<period>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: count in %q must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: period in %q must be a positive duration", s)
+	}
+	return Limit{Count: n, Period: d}, nil
+}
+
+func (l Limit) String() string {
+	return strconv.Itoa(l.Count) + "/" + l.Period.String()
+}
+
+// Result is the outcome of spending one request.
+type Result struct {
+	Allowed    bool
+	Remaining  int           // requests left right after this one
+	Reset      time.Duration // until the budget is full again
+	RetryAfter time.Duration // until a request would be allowed; zero when Allowed
+}
+
+// Take spends one request at now from a budget whose theoretical arrival time
+// is tat (zero for a key never seen). It returns the tat to store, which is
+// unchanged when the request is denied. Stores that keep the tat elsewhere
+// call this between reading and writing it.
+func (l Limit) Take(tat, now time.Time) (time.Time, Result) {
+	interval := l.Period / time.Duration(l.Count)
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	// The earliest moment next would be within the burst.
+	allowAt := next.Add(-l.Period)
+	if now.Before(allowAt) {
+		return tat, Result{
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+	return next, Result{
+		Allowed:   true,
+		Remaining: int(now.Sub(allowAt) / interval),
+		Reset:     next.Sub(now),
+	}
+}
+
+// Store keeps budgets by key. Implementations must make each Take atomic per
+// key, however many servers share the store.
+type Store interface {
+	// Take spends one request of limit from key's budget.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "10/1m", want: Limit{Count: 10, Period: time.Minute}},
+		{in: " 600 / 1h ", want: Limit{Count: 600, Period: time.Hour}},
+		{in: "10", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "ten/1m", wantErr: true},
+		{in: "10/m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTakeSpendsBurstThenRefills(t *testing.T) {
+	limit := Limit{Count: 3, Period: 3 * time.Second} // one per second
+	now := time.Date(2026, 8, 18, 10, 0, 0, 0, time.UTC)
+	var tat time.Time
+
+	for want := 2; want >= 0; want-- {
+		var res Result
+		tat, res = limit.Take(tat, now)
+		if !res.Allowed || res.Remaining != want {
+			t.Fatalf("Take() = %+v, want allowed with %d remaining", res, want)
+		}
+	}
+	if want := now.Add(3 * time.Second); !tat.Equal(want) {
+		t.Errorf("tat = %v, want %v", tat, want)
+	}
+
+	denied, res := limit.Take(tat, now.Add(400*time.Millisecond))
+	if res.Allowed {
+		t.Fatal("fourth request in the burst was allowed")
+	}
+	if !denied.Equal(tat) {
+		t.Errorf("denied request moved tat to %v", denied)
+	}
+	if res.RetryAfter != 600*time.Millisecond || res.Reset != 2600*time.Millisecond {
+		t.Errorf("denied Result = %+v, want RetryAfter 600ms, Reset 2.6s", res)
+	}
+
+	// One request's worth has refilled a second later.
+	tat, res = limit.Take(tat, now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Take() after refill = %+v, want allowed with 0 remaining", res)
+	}
+
+	// Idle for longer than the period: the budget is full, not overfull.
+	_, res = limit.Take(tat, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 || res.Reset != time.Second {
+		t.Errorf("Take() after idling = %+v, want 2 remaining, Reset 1s", res)
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	p "github.com/aarondever/go-gin-template/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	GetList(ctx context.Context, page *p.Pagination) ([]*model.APIKey, error)
+	Revoke(ctx context.Context, keyID uint64, at time.Time) error
+	TouchLastUsed(ctx context.Context, usedAt map[uint64]time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Create(key).Error; err != nil {
+		if appErr := constraintError(err); appErr != nil {
+			return appErr
+		}
+		return fmt.Errorf("create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Where("prefix = ?", prefix).Take(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, e.Wrap(err, e.CodeNotFound, "api key not found")
+		}
+		return nil, fmt.Errorf("get api key %s: %w", prefix, err)
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetList(ctx context.Context, page *p.Pagination) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	q := database.ExtractTx(ctx, r.db).WithContext(ctx).Model(&model.APIKey{}).Order("id")
+	if err := q.Scopes(database.Paginate(page)).Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("get api key list: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, keyID uint64, at time.Time) error {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("revoke api key %d: %w", keyID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return e.New(e.CodeNotFound, "api key not found")
+	}
+	return nil
+}
+
+// TouchLastUsed records a batch of usage times. A timestamp never moves
+// backwards, so an out-of-order flush cannot undo a newer one.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, usedAt map[uint64]time.Time) error {
+	db := database.ExtractTx(ctx, r.db).WithContext(ctx)
+	for keyID, at := range usedAt {
+		err := db.Model(&model.APIKey{}).
+			Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", keyID, at).
+			UpdateColumn("last_used_at", at).Error
+		if err != nil {
+			return fmt.Errorf("touch api key %d: %w", keyID, err)
+		}
+	}
+	return nil
+}
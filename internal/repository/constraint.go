@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes of the violations translated; see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	sqlStateNotNull       = "23502"
+	sqlStateForeignKey    = "23503"
+	sqlStateUnique        = "23505"
+	sqlStateCheck         = "23514"
+	sqlStateExclusion     = "23P01"
+	sqlStateStringTooLong = "22001"
+)
+
+// Constraint tells clients what a database constraint guards.
+type Constraint struct {
+	Field   string // request field it is on, e.g. "email"; none leaves the violation out
+	Message string // replaces the default message, e.g. "email already in use"
+}
+
+type constraintKey struct{ table, name string }
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = make(map[constraintKey]Constraint)
+)
+
+// RegisterConstraint describes the constraint name on table: a constraint's
+// name or, for NOT NULL, which PostgreSQL does not name, the column's.
+// Repositories register theirs in init.
+func RegisterConstraint(table, name string, c Constraint) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+	constraints[constraintKey{table, name}] = c
+}
+
+func lookupConstraint(table, name string) (Constraint, bool) {
+	constraintsMu.RLock()
+	defer constraintsMu.RUnlock()
+	c, ok := constraints[constraintKey{table, name}]
+	return c, ok
+}
+
+// constraintError translates a PostgreSQL constraint violation into the
+// client's mistake it reports, naming the field when the constraint is
+// registered. It returns nil for any other error.
+func constraintError(err error) *e.AppError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	var (
+		code    e.Code
+		message string
+		rule    string
+		reason  string
+		name    = pgErr.ConstraintName
+	)
+	switch pgErr.Code {
+	case sqlStateUnique:
+		code, message, rule, reason = e.CodeConflict, "already exists", "unique", "is already in use"
+	case sqlStateForeignKey:
+		// Deleting or re-keying a row others point at, rather than pointing
+		// at a row that is not there.
+		if strings.Contains(pgErr.Detail, "is still referenced") {
+			return e.Wrap(err, e.CodeConflict, "still referenced by other records")
+		}
+		code, message, rule, reason = e.CodeInvalidInput, "related record not found", "exists", "refers to a record that does not exist"
+	case sqlStateCheck:
+		code, message, rule, reason = e.CodeInvalidInput, "invalid value", "check", "is not allowed"
+	case sqlStateNotNull:
+		code, message, rule, reason = e.CodeInvalidInput, "missing required value", "required", "is required"
+		name = pgErr.ColumnName
+	case sqlStateExclusion:
+		code, message, rule, reason = e.CodeConflict, "conflicts with an existing record", "exclusion", "overlaps an existing record"
+	case sqlStateStringTooLong:
+		// PostgreSQL does not say which column.
+		return e.Wrap(err, e.CodeInvalidInput, "value too long")
+	default:
+		return nil
+	}
+
+	c, ok := lookupConstraint(pgErr.TableName, name)
+	if !ok && pgErr.Code == sqlStateNotNull {
+		// Columns are named like the JSON fields they hold.
+		c, ok = Constraint{Field: pgErr.ColumnName}, true
+	}
+	if c.Message != "" {
+		message = c.Message
+	}
+	appErr := e.Wrap(err, code, message)
+	if ok && c.Field != "" {
+		appErr.WithViolations([]e.Violation{{Path: "/" + c.Field, Rule: rule, Message: reason}})
+	}
+	return appErr
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestConstraintError(t *testing.T) {
+	RegisterConstraint("widgets", "widgets_owner_id_fkey", Constraint{Field: "owner_id"})
+	RegisterConstraint("widgets", "widgets_size_check", Constraint{Field: "size", Message: "size out of range"})
+	RegisterConstraint("widgets", "widgets_slot_excl", Constraint{Field: "slot"})
+
+	tests := []struct {
+		name           string
+		pgErr          *pgconn.PgError
+		wantCode       e.Code
+		wantMessage    string
+		wantViolations []e.Violation
+	}{
+		{
+			name:           "registered unique",
+			pgErr:          &pgconn.PgError{Code: "23505", TableName: "users", ConstraintName: "users_email_key"},
+			wantCode:       e.CodeConflict,
+			wantMessage:    "email already in use",
+			wantViolations: []e.Violation{{Path: "/email", Rule: "unique", Message: "is already in use"}},
+		},
+		{
+			name:        "unregistered unique",
+			pgErr:       &pgconn.PgError{Code: "23505", TableName: "widgets", ConstraintName: "widgets_name_key"},
+			wantCode:    e.CodeConflict,
+			wantMessage: "already exists",
+		},
+		{
+			name: "foreign key to a missing row",
+			pgErr: &pgconn.PgError{
+				Code: "23503", TableName: "widgets", ConstraintName: "widgets_owner_id_fkey",
+				Detail: `Key (owner_id)=(7) is not present in table "users".`,
+			},
+			wantCode:       e.CodeInvalidInput,
+			wantMessage:    "related record not found",
+			wantViolations: []e.Violation{{Path: "/owner_id", Rule: "exists", Message: "refers to a record that does not exist"}},
+		},
+		{
+			name: "foreign key still referenced",
+			pgErr: &pgconn.PgError{
+				Code: "23503", TableName: "widgets", ConstraintName: "widgets_owner_id_fkey",
+				Detail: `Key (id)=(7) is still referenced from table "widgets".`,
+			},
+			wantCode:    e.CodeConflict,
+			wantMessage: "still referenced by other records",
+		},
+		{
+			name:           "check",
+			pgErr:          &pgconn.PgError{Code: "23514", TableName: "widgets", ConstraintName: "widgets_size_check"},
+			wantCode:       e.CodeInvalidInput,
+			wantMessage:    "size out of range",
+			wantViolations: []e.Violation{{Path: "/size", Rule: "check", Message: "is not allowed"}},
+		},
+		{
+			name:           "not null names the column",
+			pgErr:          &pgconn.PgError{Code: "23502", TableName: "widgets", ColumnName: "name"},
+			wantCode:       e.CodeInvalidInput,
+			wantMessage:    "missing required value",
+			wantViolations: []e.Violation{{Path: "/name", Rule: "required", Message: "is required"}},
+		},
+		{
+			name:           "exclusion",
+			pgErr:          &pgconn.PgError{Code: "23P01", TableName: "widgets", ConstraintName: "widgets_slot_excl"},
+			wantCode:       e.CodeConflict,
+			wantMessage:    "conflicts with an existing record",
+			wantViolations: []e.Violation{{Path: "/slot", Rule: "exclusion", Message: "overlaps an existing record"}},
+		},
+		{
+			name:        "string too long",
+			pgErr:       &pgconn.PgError{Code: "22001", Message: "value too long for type character varying(10)"},
+			wantCode:    e.CodeInvalidInput,
+			wantMessage: "value too long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("gorm: %w", tt.pgErr)
+			got := constraintError(err)
+			if got == nil {
+				t.Fatal("constraintError() = nil, want an *AppError")
+			}
+			if got.Code != tt.wantCode || got.Message != tt.wantMessage {
+				t.Errorf("constraintError() = %s %q, want %s %q", got.Code, got.Message, tt.wantCode, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(got.Violations, tt.wantViolations) {
+				t.Errorf("violations = %+v, want %+v", got.Violations, tt.wantViolations)
+			}
+			if !errors.Is(got, tt.pgErr) {
+				t.Error("cause lost, want the PgError kept for the log")
+			}
+		})
+	}
+}
+
+func TestConstraintErrorIgnoresOthers(t *testing.T) {
+	for _, err := range []error{
+		errors.New("connection refused"),
+		&pgconn.PgError{Code: "40001"}, // serialization failure
+	} {
+		if got := constraintError(err); got != nil {
+			t.Errorf("constraintError(%v) = %v, want nil", err, got)
+		}
+	}
+}
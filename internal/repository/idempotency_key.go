@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	// Acquire claims key.Key for a new request and returns nil. If the key is
+	// held — finished and unexpired, or in flight and not yet lapsed — nothing
+	// is changed and the holder is returned instead.
+	Acquire(ctx context.Context, key *model.IdempotencyKey, now time.Time) (*model.IdempotencyKey, error)
+	// Complete stores the response to the request holding key.
+	Complete(ctx context.Context, key string, status int, header http.Header, body []byte) error
+	// Release gives up an unfinished key, so a retry runs the request again.
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Acquire(
+	ctx context.Context,
+	key *model.IdempotencyKey,
+	now time.Time,
+) (*model.IdempotencyKey, error) {
+	db := database.ExtractTx(ctx, r.db).WithContext(ctx)
+	// The holder may release the key between our insert and our read; then
+	// there is nothing to report and the insert is worth another try.
+	for range 3 {
+		// One upsert, so of two concurrent requests exactly one gets the key.
+		result := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"fingerprint", "status_code", "header", "body", "locked_until", "expires_at", "created_at",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{gorm.Expr(
+				"idempotency_keys.expires_at < ? OR (idempotency_keys.status_code = 0 AND idempotency_keys.locked_until < ?)",
+				now, now)}},
+		}).Create(key)
+		if result.Error != nil {
+			return nil, fmt.Errorf("acquire idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var holder model.IdempotencyKey
+		err := db.Where("key = ?", key.Key).Take(&holder).Error
+		if err == nil {
+			return &holder, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("get idempotency key: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("acquire idempotency key: released and retaken repeatedly")
+}
+
+func (r *idempotencyRepository) Complete(
+	ctx context.Context,
+	key string,
+	status int,
+	header http.Header,
+	body []byte,
+) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.IdempotencyKey{}).
+		Where("key = ? AND status_code = 0", key).
+		Updates(&model.IdempotencyKey{StatusCode: status, Header: header, Body: body}).Error
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("key = ? AND status_code = 0", key).
+		Delete(&model.IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
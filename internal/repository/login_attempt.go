@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository interface {
+	// Get returns the attempts recorded under any of keys.
+	Get(ctx context.Context, keys ...string) ([]*model.LoginAttempt, error)
+	// RecordFailure counts a failure under key at time at and returns the
+	// updated row. Failures from before resetBefore are forgotten first.
+	RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*model.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets every failure under key, unlocking it.
+	Reset(ctx context.Context, key string) error
+	// DeleteStale deletes keys whose last failure was before before.
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, keys ...string) ([]*model.LoginAttempt, error) {
+	var attempts []*model.LoginAttempt
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Where("key IN ?", keys).Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("get login attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(
+	ctx context.Context,
+	key string,
+	at, resetBefore time.Time,
+) (*model.LoginAttempt, error) {
+	// One upsert, so concurrent failures are all counted.
+	attempt := &model.LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"failures": gorm.Expr(
+						"CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END",
+						resetBefore),
+					"locked_until": gorm.Expr(
+						"CASE WHEN login_attempts.last_failure_at < ? THEN NULL ELSE login_attempts.locked_until END",
+						resetBefore),
+					"last_failure_at": at,
+				}),
+			},
+			clause.Returning{},
+		).
+		Create(attempt).Error
+	if err != nil {
+		return nil, fmt.Errorf("record login failure: %w", err)
+	}
+	return attempt, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.LoginAttempt{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+	if err != nil {
+		return fmt.Errorf("lock login key: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("key = ?", key).
+		Delete(&model.LoginAttempt{}).Error
+	if err != nil {
+		return fmt.Errorf("reset login key: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("last_failure_at < ?", before).
+		Delete(&model.LoginAttempt{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete stale login attempts: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	// Replace swaps every recovery code of userID for the given hashes.
+	Replace(ctx context.Context, userID uint64, hashes []string) error
+	// Use marks the unused code of userID with hash as used and reports
+	// whether there was one.
+	Use(ctx context.Context, userID uint64, hash string, at time.Time) (bool, error)
+	DeleteUser(ctx context.Context, userID uint64) error
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uint64, hashes []string) error {
+	if err := r.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	codes := make([]*model.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = &model.RecoveryCode{UserID: userID, Hash: hash}
+	}
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Create(codes).Error; err != nil {
+		return fmt.Errorf("create recovery codes of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uint64, hash string, at time.Time) (bool, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("use recovery code of user %d: %w", userID, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *recoveryCodeRepository) DeleteUser(ctx context.Context, userID uint64) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&model.RecoveryCode{}).Error
+	if err != nil {
+		return fmt.Errorf("delete recovery codes of user %d: %w", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	// GetByHashForUpdate locks the row until the surrounding transaction ends,
+	// so two concurrent refreshes with one token cannot both succeed.
+	GetByHashForUpdate(ctx context.Context, hash string) (*model.RefreshToken, error)
+	MarkUsed(ctx context.Context, tokenID uint64, at time.Time) error
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	RevokeUser(ctx context.Context, userID uint64, at time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Create(token).Error; err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByHashForUpdate(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("hash = ?", hash).
+		Take(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, e.Wrap(err, e.CodeNotFound, "refresh token not found")
+		}
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, tokenID uint64, at time.Time) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("id = ?", tokenID).
+		Update("used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("mark refresh token %d used: %w", tokenID, err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return fmt.Errorf("revoke refresh token family %s: %w", familyID, err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, userID uint64, at time.Time) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return fmt.Errorf("revoke refresh tokens of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.RefreshToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete expired refresh tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	e "github.com/aarondever/go-gin-template/internal/apperror"
 	"github.com/aarondever/go-gin-template/internal/database"
@@ -15,11 +16,26 @@ import (
 type Repository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, userID uint64) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetList(ctx context.Context, page *p.Pagination, filter *model.UserListFilter) ([]*model.User, error)
 	Update(ctx context.Context, user *model.User) error
+	// SetEmailVerified records when the user's email was verified, nil
+	// marking it unverified.
+	SetEmailVerified(ctx context.Context, userID uint64, at *time.Time) error
+	// SetMFA stores a sealed TOTP secret and when MFA was turned on, nil
+	// clearing either, and forgets the last TOTP step used.
+	SetMFA(ctx context.Context, userID uint64, secret *string, enabledAt *time.Time) error
+	// AdvanceMFAStep records step as the last TOTP step used unless that step
+	// or a later one already was. It reports whether it did, so each code is
+	// accepted once even under concurrent logins.
+	AdvanceMFAStep(ctx context.Context, userID uint64, step int64) (bool, error)
 	Delete(ctx context.Context, userID uint64) error
 }
 
+func init() {
+	RegisterConstraint("users", "users_email_key", Constraint{Field: "email", Message: "email already in use"})
+}
+
 type repository struct {
 	db *gorm.DB
 }
@@ -30,8 +46,8 @@ func New(db *gorm.DB) Repository {
 
 func (r *repository) Create(ctx context.Context, user *model.User) error {
 	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Create(user).Error; err != nil {
-		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			return e.Wrap(err, e.CodeConflict, "email already in use")
+		if appErr := constraintError(err); appErr != nil {
+			return appErr
 		}
 		return fmt.Errorf("create user: %w", err)
 	}
@@ -49,6 +65,17 @@ func (r *repository) GetByID(ctx context.Context, userID uint64) (*model.User, e
 	return &user, nil
 }
 
+func (r *repository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Where("email = ?", email).Take(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, e.Wrap(err, e.CodeNotFound, "user not found")
+		}
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+	return &user, nil
+}
+
 func (r *repository) GetList(ctx context.Context, page *p.Pagination, filter *model.UserListFilter) ([]*model.User, error) {
 	q := database.ExtractTx(ctx, r.db).WithContext(ctx)
 	if filter.Name != "" {
@@ -69,17 +96,53 @@ func (r *repository) GetList(ctx context.Context, page *p.Pagination, filter *mo
 func (r *repository) Update(ctx context.Context, user *model.User) error {
 	result := database.ExtractTx(ctx, r.db).WithContext(ctx).Updates(user)
 	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return e.Wrap(result.Error, e.CodeConflict, "email already in use")
+		if appErr := constraintError(result.Error); appErr != nil {
+			return appErr
 		}
 		return fmt.Errorf("update user %d: %w", user.ID, result.Error)
 	}
 	return nil
 }
 
+func (r *repository) SetEmailVerified(ctx context.Context, userID uint64, at *time.Time) error {
+	// A map, unlike a struct, lets Updates write the nil.
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.User{ID: userID}).
+		Updates(map[string]any{"email_verified_at": at}).Error
+	if err != nil {
+		return fmt.Errorf("set email verified of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *repository) SetMFA(ctx context.Context, userID uint64, secret *string, enabledAt *time.Time) error {
+	// A map, unlike a struct, lets Updates write the nils.
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.User{ID: userID}).
+		Updates(map[string]any{"mfa_secret": secret, "mfa_enabled_at": enabledAt, "mfa_last_step": 0}).Error
+	if err != nil {
+		return fmt.Errorf("set mfa of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *repository) AdvanceMFAStep(ctx context.Context, userID uint64, step int64) (bool, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND mfa_last_step < ?", userID, step).
+		Update("mfa_last_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("advance mfa step of user %d: %w", userID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (r *repository) Delete(ctx context.Context, userID uint64) error {
 	result := database.ExtractTx(ctx, r.db).WithContext(ctx).Delete(&model.User{}, userID)
 	if result.Error != nil {
+		if appErr := constraintError(result.Error); appErr != nil {
+			return appErr
+		}
 		return fmt.Errorf("delete user %d: %w", userID, result.Error)
 	}
 	return nil
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	GetByUserID(ctx context.Context, userID uint64) ([]*model.Role, error)
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) GetByUserID(ctx context.Context, userID uint64) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Find(&roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("get roles of user %d: %w", userID, err)
+	}
+	return roles, nil
+}
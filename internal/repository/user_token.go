@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *model.UserToken) error
+	// GetByHashForUpdate locks the row until the surrounding transaction ends,
+	// so a token cannot be redeemed twice concurrently.
+	GetByHashForUpdate(ctx context.Context, purpose model.TokenPurpose, hash string) (*model.UserToken, error)
+	MarkUsed(ctx context.Context, tokenID uint64, at time.Time) error
+	// InvalidateUser uses up every outstanding token of userID for purpose.
+	InvalidateUser(ctx context.Context, userID uint64, purpose model.TokenPurpose, at time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, token *model.UserToken) error {
+	if err := database.ExtractTx(ctx, r.db).WithContext(ctx).Create(token).Error; err != nil {
+		return fmt.Errorf("create user token: %w", err)
+	}
+	return nil
+}
+
+func (r *userTokenRepository) GetByHashForUpdate(
+	ctx context.Context,
+	purpose model.TokenPurpose,
+	hash string,
+) (*model.UserToken, error) {
+	var token model.UserToken
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("purpose = ? AND hash = ?", purpose, hash).
+		Take(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, e.Wrap(err, e.CodeNotFound, "token not found")
+		}
+		return nil, fmt.Errorf("get user token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *userTokenRepository) MarkUsed(ctx context.Context, tokenID uint64, at time.Time) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.UserToken{}).
+		Where("id = ?", tokenID).
+		Update("used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("mark user token %d used: %w", tokenID, err)
+	}
+	return nil
+}
+
+func (r *userTokenRepository) InvalidateUser(
+	ctx context.Context,
+	userID uint64,
+	purpose model.TokenPurpose,
+	at time.Time,
+) error {
+	err := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("invalidate %s tokens of user %d: %w", purpose, userID, err)
+	}
+	return nil
+}
+
+func (r *userTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := database.ExtractTx(ctx, r.db).WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.UserToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete expired user tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,80 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConditionalJSON writes data like JSON with 200 OK, tagged so the client can
+// revalidate it: a strong ETag hashed from the body and, unless lastModified
+// is zero, Last-Modified. When the request's If-None-Match (or, without one,
+// If-Modified-Since) shows the client already has this representation, the
+// response is 304 Not Modified with no body.
+func ConditionalJSON(c *gin.Context, data any, lastModified time.Time) {
+	body, err := json.Marshal(response{Data: data})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	etag := strongETag(body)
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Caches may keep it, but must ask before each use, and only for this caller.
+	if c.Writer.Header().Get("Cache-Control") == "" {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the request's cache validators as RFC 9110 section 13
+// orders them: If-Modified-Since counts only without If-None-Match.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have whole seconds.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether the If-None-Match list names etag. The
+// comparison is weak, so a tag weakened on the way (compression does that)
+// still matches.
+func etagMatches(list, etag string) bool {
+	for tag := range strings.SplitSeq(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var modified = time.Date(2026, 8, 18, 10, 0, 0, 500_000_000, time.UTC)
+
+func serve(t *testing.T, data any, lastModified time.Time, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/thing", func(c *gin.Context) { ConditionalJSON(c, data, lastModified) })
+	req := httptest.NewRequest(http.MethodGet, "/thing", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalJSON(t *testing.T) {
+	w := serve(t, map[string]int{"id": 1}, modified, nil)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"data":{"id":1}}` {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if len(etag) != 24 || etag[0] != '"' {
+		t.Errorf("ETag = %q, want a strong tag", etag)
+	}
+	if got := w.Header().Get("Last-Modified"); got != "Tue, 18 Aug 2026 10:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	if other := serve(t, map[string]int{"id": 2}, modified, nil).Header().Get("ETag"); other == etag {
+		t.Errorf("different bodies share ETag %q", etag)
+	}
+	if again := serve(t, map[string]int{"id": 1}, modified, nil).Header().Get("ETag"); again != etag {
+		t.Errorf("ETag = %q then %q for the same body", etag, again)
+	}
+}
+
+func TestConditionalJSONNotModified(t *testing.T) {
+	etag := serve(t, "x", modified, nil).Header().Get("ETag")
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		noLastModified bool
+		want           int
+	}{
+		{name: "no validators", want: http.StatusOK},
+		{name: "matching etag", header: http.Header{"If-None-Match": {etag}}, want: http.StatusNotModified},
+		{name: "etag in list", header: http.Header{"If-None-Match": {`"other", ` + etag}}, want: http.StatusNotModified},
+		{name: "weakened etag", header: http.Header{"If-None-Match": {"W/" + etag}}, want: http.StatusNotModified},
+		{name: "star", header: http.Header{"If-None-Match": {"*"}}, want: http.StatusNotModified},
+		{name: "stale etag", header: http.Header{"If-None-Match": {`"other"`}}, want: http.StatusOK},
+		{
+			name:   "stale etag beats fresh date",
+			header: http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {"Tue, 18 Aug 2026 10:00:00 GMT"}},
+			want:   http.StatusOK,
+		},
+		{name: "same second", header: http.Header{"If-Modified-Since": {"Tue, 18 Aug 2026 10:00:00 GMT"}}, want: http.StatusNotModified},
+		{name: "later date", header: http.Header{"If-Modified-Since": {"Wed, 19 Aug 2026 10:00:00 GMT"}}, want: http.StatusNotModified},
+		{name: "earlier date", header: http.Header{"If-Modified-Since": {"Tue, 18 Aug 2026 09:59:59 GMT"}}, want: http.StatusOK},
+		{name: "bad date", header: http.Header{"If-Modified-Since": {"yesterday"}}, want: http.StatusOK},
+		{
+			name:           "date without last modified",
+			header:         http.Header{"If-Modified-Since": {"Wed, 19 Aug 2026 10:00:00 GMT"}},
+			noLastModified: true,
+			want:           http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastModified := modified
+			if tt.noLastModified {
+				lastModified = time.Time{}
+			}
+			w := serve(t, "x", lastModified, tt.header)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified {
+				if w.Body.Len() != 0 {
+					t.Errorf("304 body = %q, want none", w.Body)
+				}
+				if w.Header().Get("ETag") != etag {
+					t.Errorf("304 ETag = %q, want %q", w.Header().Get("ETag"), etag)
+				}
+			}
+		})
+	}
+}
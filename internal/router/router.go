@@ -3,10 +3,17 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/concurrency"
 	"github.com/aarondever/go-gin-template/internal/handler"
 	"github.com/aarondever/go-gin-template/internal/middleware"
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/aarondever/go-gin-template/internal/ratelimit"
+	"github.com/aarondever/go-gin-template/internal/repository"
 	"github.com/aarondever/go-gin-template/internal/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -15,45 +22,199 @@ import (
 
 const healthPath = "/health"
 
+// authBodyLimit is the body limit on /v1/auth, whose requests are a few short
+// fields and need no credentials to send.
+const authBodyLimit = 16 << 10
+
+// priority lets health checks jump the queue when the server is at capacity,
+// so it can still be probed. It runs before authentication, so it goes by
+// nothing a caller could claim without credentials, such as an admin route.
+func priority(c *gin.Context) concurrency.Priority {
+	if c.FullPath() == healthPath {
+		return concurrency.High
+	}
+	return concurrency.Normal
+}
+
 func SetupRouter(
 	cfg *config.Config,
 	h *handler.Handler,
-) *gin.Engine {
+	apiKeys *handler.APIKeyHandler,
+	authH *handler.AuthHandler,
+	accounts *handler.AccountHandler,
+	mfa *handler.MFAHandler,
+	authenticators []auth.Authenticator,
+	authorizer auth.Authorizer,
+	limits ratelimit.Store,
+	idempotencyKeys repository.IdempotencyRepository,
+) (*gin.Engine, error) {
 	gin.SetMode(cfg.Server.Mode)
 
 	// So bind errors and manual ValidateStruct errors name fields identically.
 	validation.UseFieldNames(binding.Validator.Engine())
 
+	// The 504 envelope has to be written before the server drops the socket.
+	if cfg.Server.WriteTimeout > 0 && cfg.Server.RequestTimeout >= cfg.Server.WriteTimeout {
+		return nil, fmt.Errorf("SERVER_REQUEST_TIMEOUT must be below SERVER_WRITE_TIMEOUT")
+	}
+	// Otherwise a retry could take over a key whose request is still running.
+	if cfg.Idempotency.LockTimeout <= cfg.Server.RequestTimeout {
+		return nil, fmt.Errorf("IDEMPOTENCY_LOCK_TIMEOUT must be above SERVER_REQUEST_TIMEOUT")
+	}
+	// The same holds for each route's own deadline.
+	for route, d := range cfg.Server.RouteTimeouts {
+		switch {
+		case d <= 0:
+			return nil, fmt.Errorf("SERVER_ROUTE_TIMEOUTS: %s must be positive", route)
+		case cfg.Server.WriteTimeout > 0 && d >= cfg.Server.WriteTimeout:
+			return nil, fmt.Errorf("SERVER_ROUTE_TIMEOUTS: %s must be below SERVER_WRITE_TIMEOUT", route)
+		case cfg.Idempotency.LockTimeout <= d:
+			return nil, fmt.Errorf("SERVER_ROUTE_TIMEOUTS: %s must be below IDEMPOTENCY_LOCK_TIMEOUT", route)
+		}
+	}
+
+	errorFormat, err := middleware.ParseErrorFormat(cfg.Server.ErrorFormat)
+	if err != nil {
+		return nil, fmt.Errorf("SERVER_ERROR_FORMAT: %w", err)
+	}
+	localizer, err := e.NewLocalizer(cfg.Server.DefaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("SERVER_DEFAULT_LOCALE: %w", err)
+	}
+
+	cors := func(c *gin.Context) { c.Next() }
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		if cors, err = middleware.CORS(cfg.CORS); err != nil {
+			return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err)
+		}
+	}
+
+	concurrencyLimit := func(c *gin.Context) { c.Next() }
+	if cfg.Concurrency.Limit > 0 {
+		limiter, err := concurrency.NewLimiter(cfg.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("CONCURRENCY_*: %w", err)
+		}
+		concurrencyLimit = middleware.ConcurrencyLimit(limiter, priority)
+	}
+
 	r := gin.New()
+	// c.ClientIP() feeds login lockouts, so X-Forwarded-For is only believed
+	// from configured proxies; gin's default believes anyone.
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("SERVER_TRUSTED_PROXIES: %w", err)
+	}
 
 	r.Use(
 		otelgin.Middleware(cfg.OTEL.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
 			return r.URL.Path != healthPath
 		})),
+		middleware.RequestID(),
+		middleware.SecurityHeaders(cfg.Server),
 		middleware.Logger(healthPath),
-		middleware.ErrorHandler(),
+		middleware.Compress(cfg.Server.CompressMin),
+		middleware.ErrorHandler(middleware.ErrorOptions{
+			Format:                  errorFormat,
+			LegacyValidationDetails: cfg.Server.LegacyDetails,
+			Localizer:               localizer,
+		}),
 		// Skips gin's bare 500, so a panic unwinds into ErrorHandler and gets the
 		// same envelope as every other failure.
 		gin.CustomRecovery(func(c *gin.Context, err any) {
 			c.Error(fmt.Errorf("panic: %v", err))
 		}),
+		// Ahead of everything that can turn a request away, so browsers can read
+		// a 503, 504 or 413 too, and preflights are answered before they queue.
+		// It needs ErrorHandler before it to write its own 403.
+		cors,
+		// Before Timeout, so time spent queuing is not taken from the handler.
+		concurrencyLimit,
+		// Routes needing a different deadline or body limit add their own.
+		middleware.Timeout(cfg.Server.RequestTimeout),
+		middleware.RouteTimeouts(cfg.Server.RouteTimeouts),
+		middleware.BodyLimit(cfg.Server.MaxBodyBytes),
 	)
+	if cfg.Server.StrictJSON {
+		r.Use(params.StrictJSON())
+	}
 
 	r.GET(healthPath, func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	v1 := r.Group("/v1")
+	// Each limit is a budget shared by the routes it is attached to.
+	limit := func(name, spec string, key middleware.RateKeyFunc) (gin.HandlerFunc, error) {
+		if !cfg.RateLimit.Enabled {
+			return func(c *gin.Context) { c.Next() }, nil
+		}
+		l, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		return middleware.RateLimit(limits, name, l, key), nil
+	}
+	authLimit, err := limit("auth", cfg.RateLimit.Auth, middleware.ByClientIP)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_AUTH: %w", err)
+	}
+	apiLimit, err := limit("api", cfg.RateLimit.API, middleware.ByCaller)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_API: %w", err)
+	}
+
+	authenticate := middleware.Authenticate(authenticators...)
+	idempotent := middleware.Idempotency(idempotencyKeys, cfg.Idempotency)
+	can := func(action auth.Permission) gin.HandlerFunc {
+		return middleware.Authorize(authorizer, action, nil)
+	}
+	// Users may read and update their own record without holding the permission.
+	canOnSelf := func(action auth.Permission) gin.HandlerFunc {
+		return middleware.Authorize(authorizer, action, middleware.PathOwner(auth.ResourceUser, "userID"))
+	}
+
+	v1 := r.Group("/v1", middleware.RequireJSON())
 	{
-		users := v1.Group("/users")
+		authn := v1.Group("/auth", authLimit, middleware.BodyLimit(authBodyLimit))
 		{
-			users.POST("", h.Create)
-			users.GET("/:userID", h.GetByID)
-			users.GET("", h.GetList)
-			users.PUT("/:userID", h.Update)
-			users.DELETE("/:userID", h.Delete)
+			authn.POST("/login", authH.Login)
+			authn.POST("/refresh", authH.Refresh)
+			authn.POST("/logout", authH.Logout)
+			authn.POST("/mfa/verify", authH.VerifyMFA)
+			authn.POST("/signup", accounts.Signup)
+			authn.POST("/verify-email/request", accounts.RequestVerification)
+			authn.POST("/verify-email/confirm", accounts.ConfirmVerification)
+			authn.POST("/password-reset/request", accounts.RequestPasswordReset)
+			authn.POST("/password-reset/confirm", accounts.ConfirmPasswordReset)
+		}
+
+		users := v1.Group("/users", authenticate, apiLimit, idempotent)
+		{
+			users.POST("", can(auth.PermUsersWrite), h.Create)
+			users.GET("/:userID", canOnSelf(auth.PermUsersRead), h.GetByID)
+			users.GET("", can(auth.PermUsersRead), h.GetList)
+			users.PUT("/:userID", canOnSelf(auth.PermUsersWrite), h.Update)
+			users.DELETE("/:userID", can(auth.PermUsersWrite), h.Delete)
+			users.DELETE("/:userID/sessions", canOnSelf(auth.PermUsersWrite), authH.LogoutAll)
+			users.POST("/:userID/mfa/totp", canOnSelf(auth.PermUsersWrite), mfa.EnrollTOTP)
+			users.POST("/:userID/mfa/totp/confirm", canOnSelf(auth.PermUsersWrite), mfa.ConfirmTOTP)
+			users.DELETE("/:userID/mfa", canOnSelf(auth.PermUsersWrite), mfa.Disable)
+			users.DELETE("/:userID/lockout", can(auth.PermUsersWrite), authH.Unlock)
+		}
+
+		keys := v1.Group("/api-keys", authenticate, apiLimit, can(auth.PermAPIKeysManage), idempotent)
+		{
+			keys.POST("", apiKeys.Create)
+			keys.GET("", apiKeys.GetList)
+			keys.DELETE("/:keyID", apiKeys.Revoke)
+		}
+	}
+
+	// A misspelt route would otherwise keep the default deadline unnoticed.
+	for prefix := range cfg.Server.RouteTimeouts {
+		if !slices.ContainsFunc(r.Routes(), func(route gin.RouteInfo) bool { return middleware.UnderRoute(route.Path, prefix) }) {
+			return nil, fmt.Errorf("SERVER_ROUTE_TIMEOUTS: no route under %s", prefix)
 		}
 	}
 
-	return r
+	return r, nil
 }
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	"github.com/aarondever/go-gin-template/internal/concurrency"
+	"github.com/aarondever/go-gin-template/internal/params"
+	"github.com/gin-gonic/gin"
+)
+
+// testConfig is the least SetupRouter accepts, with no CORS and no limits but
+// a body size one.
+func testConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Mode:           gin.TestMode,
+			WriteTimeout:   time.Hour,
+			RequestTimeout: time.Minute,
+			ErrorFormat:    "envelope",
+			DefaultLocale:  "en",
+			MaxBodyBytes:   1 << 20,
+		},
+		Idempotency: config.IdempotencyConfig{LockTimeout: 2 * time.Hour},
+	}
+}
+
+func setup(t *testing.T, cfg *config.Config) (*gin.Engine, error) {
+	t.Helper()
+	return SetupRouter(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+func TestRouteTimeoutOverridesRequestTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.RouteTimeouts = map[string]time.Duration{"/v1/auth": 30 * time.Minute}
+	r, err := setup(t, cfg)
+	if err != nil {
+		t.Fatalf("SetupRouter() error = %v", err)
+	}
+
+	deadlines := map[string]time.Time{}
+	probe := func(c *gin.Context) { deadlines[c.FullPath()], _ = c.Request.Context().Deadline() }
+	// Added after SetupRouter, so they get its global middleware.
+	r.GET("/v1/auth/probe", probe)
+	r.GET("/v1/other/probe", probe)
+
+	tests := []struct {
+		route string
+		want  time.Duration
+	}{
+		{route: "/v1/auth/probe", want: 30 * time.Minute},
+		{route: "/v1/other/probe", want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.route, nil))
+			deadline, ok := deadlines[tt.route]
+			if !ok {
+				t.Fatal("handler not reached")
+			}
+			if until := time.Until(deadline); until > tt.want || until < tt.want-10*time.Second {
+				t.Errorf("deadline in %v, want about %v", until, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutsRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts map[string]time.Duration
+		want     string
+	}{
+		{name: "no such route", timeouts: map[string]time.Duration{"/v1/nope": time.Second}, want: "no route under /v1/nope"},
+		{name: "not positive", timeouts: map[string]time.Duration{"/v1/auth": 0}, want: "must be positive"},
+		{name: "past write timeout", timeouts: map[string]time.Duration{"/v1/auth": time.Hour}, want: "below SERVER_WRITE_TIMEOUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Server.RouteTimeouts = tt.timeouts
+			if _, err := setup(t, cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SetupRouter() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictJSONPerRouter(t *testing.T) {
+	strictCfg := testConfig()
+	strictCfg.Server.StrictJSON = true
+	strict, err := setup(t, strictCfg)
+	if err != nil {
+		t.Fatalf("SetupRouter() error = %v", err)
+	}
+	lenient, err := setup(t, testConfig())
+	if err != nil {
+		t.Fatalf("SetupRouter() error = %v", err)
+	}
+
+	bind := func(c *gin.Context) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := params.BindJSON(c, &body); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+	tests := []struct {
+		name string
+		r    *gin.Engine
+		want int
+	}{
+		{name: "strict", r: strict, want: http.StatusBadRequest},
+		{name: "lenient", r: lenient, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		tt.r.POST("/v1/probe", bind)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/probe", strings.NewReader(`{"name":"Ada","role":"admin"}`))
+			req.Header.Set("Content-Type", "application/json")
+			tt.r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriority(t *testing.T) {
+	r, err := setup(t, testConfig())
+	if err != nil {
+		t.Fatalf("SetupRouter() error = %v", err)
+	}
+	got := concurrency.High
+	r.GET("/v1/api-keys/probe", func(c *gin.Context) { got = priority(c) })
+
+	// Priority is picked before authentication, so an admin route gets no head
+	// start: anyone could claim it.
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/api-keys/probe", nil))
+	if got != concurrency.Normal {
+		t.Errorf("priority(/v1/api-keys/probe) = %d, want Normal", got)
+	}
+}
+
+func TestCORSHeadersOnRejectedRequests(t *testing.T) {
+	cfg := testConfig()
+	cfg.CORS = config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}
+	r, err := setup(t, cfg)
+	if err != nil {
+		t.Fatalf("SetupRouter() error = %v", err)
+	}
+	r.POST("/v1/probe", func(c *gin.Context) {
+		var body struct{}
+		if err := params.BindJSON(c, &body); err != nil {
+			c.Error(err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/probe", strings.NewReader(`"`+strings.Repeat("a", 2<<20)+`"`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://app.example.com")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin so the browser can read the error", got)
+	}
+}
+
+func TestCORSCredentialsNeedExactOrigins(t *testing.T) {
+	cfg := testConfig()
+	cfg.CORS = config.CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}
+	if _, err := setup(t, cfg); err == nil || !strings.Contains(err.Error(), "CORS_ALLOWED_ORIGINS") {
+		t.Errorf("SetupRouter() error = %v, want a CORS_ALLOWED_ORIGINS one", err)
+	}
+}
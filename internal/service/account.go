@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/mailer"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// Paths on MAIL_LINK_BASE_URL that emailed links point at. The front end
+// serving them reads the token query parameter and posts it back.
+const (
+	verifyEmailPath   = "/verify-email"
+	resetPasswordPath = "/reset-password"
+)
+
+// mailSendTimeout bounds a background send.
+const mailSendTimeout = 30 * time.Second
+
+type AccountService interface {
+	// Signup creates a user who can log in and mails them a verification link.
+	// Emails are written in the best match for acceptLanguage, an
+	// Accept-Language header value.
+	Signup(ctx context.Context, user *model.User, password, acceptLanguage string) (*model.User, error)
+	// RequestVerification mails a new verification link if email belongs to
+	// a user who has not verified it. It never reports whether it did.
+	RequestVerification(ctx context.Context, email, acceptLanguage string) error
+	ConfirmVerification(ctx context.Context, token string) error
+	// RequestPasswordReset mails a reset link if email belongs to a user. It
+	// never reports whether it did.
+	RequestPasswordReset(ctx context.Context, email, acceptLanguage string) error
+	// ConfirmPasswordReset sets a new password and ends every session.
+	ConfirmPasswordReset(ctx context.Context, token, password string) error
+	// PurgeExpired deletes verification and reset tokens past their expiry.
+	PurgeExpired(ctx context.Context) (int64, error)
+	// Close waits for emails still being sent.
+	Close()
+}
+
+type accountService struct {
+	users     repository.Repository
+	tokens    repository.UserTokenRepository
+	refresh   repository.RefreshTokenRepository
+	tx        database.TxManager
+	passwords *auth.PasswordHasher
+	mail      mailer.Mailer
+	templates *mailer.Templates
+
+	linkBase  string
+	verifyTTL time.Duration
+	resetTTL  time.Duration
+	now       func() time.Time
+
+	// Links are made and mailed in the background so that how long a request
+	// takes does not reveal whether an email was sent.
+	sending sync.WaitGroup
+}
+
+func NewAccountService(
+	users repository.Repository,
+	tokens repository.UserTokenRepository,
+	refresh repository.RefreshTokenRepository,
+	tx database.TxManager,
+	passwords *auth.PasswordHasher,
+	mail mailer.Mailer,
+	templates *mailer.Templates,
+	authCfg config.AuthConfig,
+	mailCfg config.MailConfig,
+) AccountService {
+	return &accountService{
+		users:     users,
+		tokens:    tokens,
+		refresh:   refresh,
+		tx:        tx,
+		passwords: passwords,
+		mail:      mail,
+		templates: templates,
+		linkBase:  strings.TrimRight(mailCfg.LinkBaseURL, "/"),
+		verifyTTL: authCfg.VerifyEmailTTL,
+		resetTTL:  authCfg.PasswordResetTTL,
+		now:       time.Now,
+	}
+}
+
+func errInvalidLink() error {
+	return e.New(e.CodeInvalidInput, "invalid or expired link")
+}
+
+func (s *accountService) Signup(ctx context.Context, user *model.User, password, acceptLanguage string) (*model.User, error) {
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("service.Signup: %w", err)
+	}
+	user.PasswordHash = &hash
+	user.EmailVerifiedAt = nil
+
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("service.Signup: %w", err)
+	}
+	if err := s.sendLink(ctx, user, model.TokenVerifyEmail, acceptLanguage); err != nil {
+		return nil, fmt.Errorf("service.Signup: %w", err)
+	}
+	return user, nil
+}
+
+func (s *accountService) RequestVerification(ctx context.Context, email, acceptLanguage string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("service.RequestVerification: %w", err)
+	}
+	if user.EmailVerifiedAt == nil {
+		s.sendLinkLater(ctx, user, model.TokenVerifyEmail, acceptLanguage)
+	}
+	return nil
+}
+
+func (s *accountService) ConfirmVerification(ctx context.Context, token string) error {
+	err := s.redeem(ctx, model.TokenVerifyEmail, token, func(ctx context.Context, user *model.User, t *model.UserToken) error {
+		// A link sent before the address changed does not verify the new one.
+		if user.Email == nil || *user.Email != t.Email {
+			return errInvalidLink()
+		}
+		now := s.now()
+		return s.users.Update(ctx, &model.User{ID: user.ID, EmailVerifiedAt: &now})
+	})
+	if err != nil {
+		return fmt.Errorf("service.ConfirmVerification: %w", err)
+	}
+	return nil
+}
+
+func (s *accountService) RequestPasswordReset(ctx context.Context, email, acceptLanguage string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("service.RequestPasswordReset: %w", err)
+	}
+	s.sendLinkLater(ctx, user, model.TokenResetPassword, acceptLanguage)
+	return nil
+}
+
+func (s *accountService) ConfirmPasswordReset(ctx context.Context, token, password string) error {
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return fmt.Errorf("service.ConfirmPasswordReset: %w", err)
+	}
+
+	err = s.redeem(ctx, model.TokenResetPassword, token, func(ctx context.Context, user *model.User, t *model.UserToken) error {
+		update := &model.User{ID: user.ID, PasswordHash: &hash}
+		// The reset link reached this address, which proves it as well as a
+		// verification link would.
+		if user.EmailVerifiedAt == nil && user.Email != nil && *user.Email == t.Email {
+			now := s.now()
+			update.EmailVerifiedAt = &now
+		}
+		if err := s.users.Update(ctx, update); err != nil {
+			return err
+		}
+		// Whoever knew the old password must not stay logged in.
+		return s.refresh.RevokeUser(ctx, user.ID, s.now())
+	})
+	if err != nil {
+		return fmt.Errorf("service.ConfirmPasswordReset: %w", err)
+	}
+	return nil
+}
+
+func (s *accountService) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.tokens.DeleteExpired(ctx, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("service.PurgeExpiredUserTokens: %w", err)
+	}
+	return n, nil
+}
+
+func (s *accountService) Close() {
+	s.sending.Wait()
+}
+
+// redeem uses up token and runs apply in the same transaction.
+func (s *accountService) redeem(
+	ctx context.Context,
+	purpose model.TokenPurpose,
+	token string,
+	apply func(ctx context.Context, user *model.User, t *model.UserToken) error,
+) error {
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		t, err := s.tokens.GetByHashForUpdate(ctx, purpose, auth.HashOpaqueToken(token))
+		if err != nil {
+			if isNotFound(err) {
+				return errInvalidLink()
+			}
+			return err
+		}
+		if t.UsedAt != nil || !s.now().Before(t.ExpiresAt) {
+			return errInvalidLink()
+		}
+
+		user, err := s.users.GetByID(ctx, t.UserID)
+		if err != nil {
+			if isNotFound(err) {
+				return errInvalidLink()
+			}
+			return err
+		}
+
+		if err := s.tokens.MarkUsed(ctx, t.ID, s.now()); err != nil {
+			return err
+		}
+		return apply(ctx, user, t)
+	})
+}
+
+// sendLink replaces any outstanding purpose token of user with a new one and
+// mails it in the background.
+func (s *accountService) sendLink(ctx context.Context, user *model.User, purpose model.TokenPurpose, acceptLanguage string) error {
+	msg, err := s.newLink(ctx, user, purpose, acceptLanguage)
+	if err != nil || msg == nil {
+		return err
+	}
+
+	// Detached from the request, which ends before the mail is out.
+	sendCtx := context.WithoutCancel(ctx)
+	s.sending.Go(func() {
+		sendCtx, cancel := context.WithTimeout(sendCtx, mailSendTimeout)
+		defer cancel()
+		if err := s.mail.Send(sendCtx, msg); err != nil {
+			logger.ErrorContext(sendCtx, "send "+string(purpose)+" email", logger.Err(err))
+		}
+	})
+	return nil
+}
+
+// sendLinkLater does all of sendLink's work in the background: the caller
+// answers without waiting on the database or the template, so a registered
+// email takes as long as an unknown one.
+func (s *accountService) sendLinkLater(ctx context.Context, user *model.User, purpose model.TokenPurpose, acceptLanguage string) {
+	// Detached from the request, which ends before the mail is out.
+	sendCtx := context.WithoutCancel(ctx)
+	s.sending.Go(func() {
+		sendCtx, cancel := context.WithTimeout(sendCtx, mailSendTimeout)
+		defer cancel()
+		msg, err := s.newLink(sendCtx, user, purpose, acceptLanguage)
+		if err == nil && msg != nil {
+			err = s.mail.Send(sendCtx, msg)
+		}
+		if err != nil {
+			logger.ErrorContext(sendCtx, "send "+string(purpose)+" email", logger.Err(err))
+		}
+	})
+}
+
+// newLink replaces any outstanding purpose token of user with a new one and
+// renders the email linking to it; nil when user has no email.
+func (s *accountService) newLink(ctx context.Context, user *model.User, purpose model.TokenPurpose, acceptLanguage string) (*mailer.Message, error) {
+	if user.Email == nil {
+		return nil, nil
+	}
+
+	template, path, ttl := mailer.TemplateVerifyEmail, verifyEmailPath, s.verifyTTL
+	if purpose == model.TokenResetPassword {
+		template, path, ttl = mailer.TemplateResetPassword, resetPasswordPath, s.resetTTL
+	}
+
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		now := s.now()
+		if err := s.tokens.InvalidateUser(ctx, user.ID, purpose, now); err != nil {
+			return err
+		}
+		return s.tokens.Create(ctx, &model.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			Hash:      auth.HashOpaqueToken(token),
+			Email:     *user.Email,
+			ExpiresAt: now.Add(ttl),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templates.Render(s.templates.Match(acceptLanguage), template, *user.Email, mailer.LinkData{
+		Name:  user.Name,
+		Link:  s.linkBase + path + "?token=" + url.QueryEscape(token),
+		Hours: int(math.Ceil(ttl.Hours())),
+	})
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/mailer"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeUserTokenRepo keeps verification, reset and challenge tokens in memory.
+type fakeUserTokenRepo struct {
+	repository.UserTokenRepository
+
+	byHash map[string]*model.UserToken
+	nextID uint64
+}
+
+func (f *fakeUserTokenRepo) Create(_ context.Context, token *model.UserToken) error {
+	f.nextID++
+	token.ID = f.nextID
+	f.byHash[token.Hash] = token
+	return nil
+}
+
+func (f *fakeUserTokenRepo) GetByHashForUpdate(_ context.Context, purpose model.TokenPurpose, hash string) (*model.UserToken, error) {
+	token, ok := f.byHash[hash]
+	if !ok || token.Purpose != purpose {
+		return nil, e.New(e.CodeNotFound, "token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (f *fakeUserTokenRepo) MarkUsed(_ context.Context, tokenID uint64, at time.Time) error {
+	for _, t := range f.byHash {
+		if t.ID == tokenID {
+			t.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserTokenRepo) InvalidateUser(_ context.Context, userID uint64, purpose model.TokenPurpose, at time.Time) error {
+	for _, t := range f.byHash {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+type accountFixture struct {
+	svc     *accountService
+	users   *fakeUserRepo
+	tokens  *fakeUserTokenRepo
+	refresh *fakeRefreshRepo
+	mail    *mailer.Memory
+	auth    *authFixture
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+	cfg := testAuthConfig
+	cfg.VerifyEmailTTL = 48 * time.Hour
+	cfg.PasswordResetTTL = time.Hour
+
+	// The account service shares the auth fixture's users and refresh tokens,
+	// so tests can log in with what it sets.
+	af := newAuthFixture(t, cfg)
+	templates, err := mailer.NewTemplates("en")
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	mail := mailer.NewMemory()
+	svc := NewAccountService(af.users, af.userTokens, af.refresh, fakeTx{}, af.passwords, mail, templates,
+		cfg, config.MailConfig{LinkBaseURL: "https://app.example.com/"}).(*accountService)
+	return &accountFixture{svc: svc, users: af.users, tokens: af.userTokens, refresh: af.refresh, mail: mail, auth: af}
+}
+
+var linkToken = regexp.MustCompile(`\?token=([^\s"&]+)`)
+
+// sentToken waits for outstanding mail and returns the token linked in the
+// last message.
+func (f *accountFixture) sentToken(t *testing.T, wantPath string) string {
+	t.Helper()
+	f.svc.Close()
+	sent := f.mail.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	text := sent[len(sent)-1].Text
+	if !regexp.MustCompile(regexp.QuoteMeta("https://app.example.com" + wantPath + "?token=")).MatchString(text) {
+		t.Fatalf("email does not link to %s:\n%s", wantPath, text)
+	}
+	token, err := url.QueryUnescape(linkToken.FindStringSubmatch(text)[1])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func (f *accountFixture) signup(t *testing.T, email string) *model.User {
+	t.Helper()
+	user, err := f.svc.Signup(context.Background(), &model.User{Name: "Ada", Email: &email}, "old password 1", "")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	// Sends run in the background; keep them in order.
+	f.svc.Close()
+	return user
+}
+
+func assertInvalidLink(t *testing.T, err error) {
+	t.Helper()
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) || appErr.Code != e.CodeInvalidInput || appErr.Message != "invalid or expired link" {
+		t.Fatalf("error = %v, want INVALID_INPUT invalid or expired link", err)
+	}
+}
+
+func TestSignupVerifiesEmail(t *testing.T) {
+	f := newAccountFixture(t)
+	user := f.signup(t, "ada@example.com")
+
+	if user.ID == 0 || user.PasswordHash == nil || user.EmailVerifiedAt != nil {
+		t.Fatalf("Signup() = %+v, want a stored, unverified user with a password", user)
+	}
+	token := f.sentToken(t, "/verify-email")
+	if sent := f.mail.Sent(); len(sent) != 1 || sent[0].To != "ada@example.com" {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	if err := f.svc.ConfirmVerification(context.Background(), token); err != nil {
+		t.Fatalf("ConfirmVerification() error = %v", err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("EmailVerifiedAt not set")
+	}
+
+	assertInvalidLink(t, f.svc.ConfirmVerification(context.Background(), token))
+}
+
+func TestConfirmVerificationRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *accountFixture, user *model.User)
+	}{
+		{name: "expired", setup: func(f *accountFixture, _ *model.User) {
+			f.svc.now = func() time.Time { return time.Now().Add(49 * time.Hour) }
+		}},
+		{name: "address changed", setup: func(_ *accountFixture, user *model.User) {
+			changed := "grace@example.com"
+			user.Email = &changed
+		}},
+		{name: "superseded", setup: func(f *accountFixture, user *model.User) {
+			if err := f.svc.RequestVerification(context.Background(), *user.Email, ""); err != nil {
+				t.Fatalf("RequestVerification() error = %v", err)
+			}
+			f.svc.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAccountFixture(t)
+			user := f.signup(t, "ada@example.com")
+			token := f.sentToken(t, "/verify-email")
+
+			tt.setup(f, user)
+			assertInvalidLink(t, f.svc.ConfirmVerification(context.Background(), token))
+			if user.EmailVerifiedAt != nil {
+				t.Error("EmailVerifiedAt set")
+			}
+		})
+	}
+
+	f := newAccountFixture(t)
+	assertInvalidLink(t, f.svc.ConfirmVerification(context.Background(), "unknown"))
+}
+
+func TestRequestsForUnknownEmailSendNothing(t *testing.T) {
+	f := newAccountFixture(t)
+
+	if err := f.svc.RequestVerification(context.Background(), "nobody@example.com", ""); err != nil {
+		t.Errorf("RequestVerification() error = %v", err)
+	}
+	if err := f.svc.RequestPasswordReset(context.Background(), "nobody@example.com", ""); err != nil {
+		t.Errorf("RequestPasswordReset() error = %v", err)
+	}
+	f.svc.Close()
+	if sent := f.mail.Sent(); len(sent) != 0 {
+		t.Errorf("sent %d emails, want 0", len(sent))
+	}
+}
+
+func TestRequestVerificationSkipsVerifiedEmail(t *testing.T) {
+	f := newAccountFixture(t)
+	user := f.signup(t, "ada@example.com")
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	if err := f.svc.RequestVerification(context.Background(), "ada@example.com", ""); err != nil {
+		t.Fatalf("RequestVerification() error = %v", err)
+	}
+	f.svc.Close()
+	if sent := f.mail.Sent(); len(sent) != 1 {
+		t.Errorf("sent %d emails, want only the signup one", len(sent))
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	f := newAccountFixture(t)
+	user := f.signup(t, "ada@example.com")
+	login, err := f.auth.svc.Login(context.Background(), "ada@example.com", "old password 1", testIP)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if err := f.svc.RequestPasswordReset(context.Background(), "ada@example.com", "es"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	token := f.sentToken(t, "/reset-password")
+	if sent := f.mail.Sent(); sent[len(sent)-1].Subject == "Reset your password" {
+		t.Error("reset email not localized for Accept-Language es")
+	}
+
+	if err := f.svc.ConfirmPasswordReset(context.Background(), token, "new password 2"); err != nil {
+		t.Fatalf("ConfirmPasswordReset() error = %v", err)
+	}
+
+	if f.refresh.active(login.Tokens.RefreshToken) {
+		t.Error("refresh token survived the reset")
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("reset did not verify the email it was sent to")
+	}
+	if _, err := f.auth.svc.Login(context.Background(), "ada@example.com", "old password 1", testIP); err == nil {
+		t.Error("old password still works")
+	}
+	if _, err := f.auth.svc.Login(context.Background(), "ada@example.com", "new password 2", testIP); err != nil {
+		t.Errorf("Login(new password) error = %v", err)
+	}
+
+	assertInvalidLink(t, f.svc.ConfirmPasswordReset(context.Background(), token, "new password 3"))
+}
+
+// A verification token is not a reset token, even for the same user.
+func TestConfirmPasswordResetRejectsVerificationToken(t *testing.T) {
+	f := newAccountFixture(t)
+	f.signup(t, "ada@example.com")
+	token := f.sentToken(t, "/verify-email")
+
+	assertInvalidLink(t, f.svc.ConfirmPasswordReset(context.Background(), token, "new password 2"))
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeAPIKeyRepo serves keys from memory and records last-used flushes.
+type fakeAPIKeyRepo struct {
+	repository.APIKeyRepository
+
+	mu      sync.Mutex
+	keys    map[string]*model.APIKey
+	touched map[uint64]time.Time
+	getErr  error
+}
+
+func (f *fakeAPIKeyRepo) GetByPrefix(_ context.Context, prefix string) (*model.APIKey, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	key, ok := f.keys[prefix]
+	if !ok {
+		return nil, e.New(e.CodeNotFound, "api key not found")
+	}
+	return key, nil
+}
+
+func (f *fakeAPIKeyRepo) Create(_ context.Context, key *model.APIKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[key.Prefix] = key
+	return nil
+}
+
+func (f *fakeAPIKeyRepo) TouchLastUsed(_ context.Context, usedAt map[uint64]time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, at := range usedAt {
+		f.touched[id] = at
+	}
+	return nil
+}
+
+var keyNow = time.Date(2026, 8, 18, 10, 0, 0, 0, time.UTC)
+
+// newKeyFixture stores one key built by mutate and returns its plaintext.
+func newKeyFixture(t *testing.T, mutate func(*model.APIKey)) (*apiKeyService, *fakeAPIKeyRepo, string) {
+	t.Helper()
+	plaintext, lookup, err := auth.GenerateAPIKey("gk")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	key := &model.APIKey{ID: 7, Prefix: lookup, Hash: auth.HashAPIKey(plaintext), Scopes: []string{"users:read"}}
+	if mutate != nil {
+		mutate(key)
+	}
+
+	repo := &fakeAPIKeyRepo{keys: map[string]*model.APIKey{lookup: key}, touched: map[uint64]time.Time{}}
+	svc := NewAPIKeyService(repo, NewAuthorizer(&fakeRoleRepo{}), "gk").(*apiKeyService)
+	svc.now = func() time.Time { return keyNow }
+	t.Cleanup(svc.Close)
+	return svc, repo, plaintext
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	svc, repo, plaintext := newKeyFixture(t, nil)
+
+	p, err := svc.ResolveAPIKey(context.Background(), plaintext)
+	if err != nil {
+		t.Fatalf("ResolveAPIKey() error = %v", err)
+	}
+	if p.Kind != auth.KindAPIKey || p.Subject != "7" {
+		t.Errorf("principal = %+v, want api key 7", p)
+	}
+	if len(p.Scopes) != 1 || p.Scopes[0] != "users:read" {
+		t.Errorf("scopes = %v, want [users:read]", p.Scopes)
+	}
+
+	// Usage is written asynchronously; Close forces the final flush.
+	svc.Close()
+	if got := repo.touched[7]; !got.Equal(keyNow) {
+		t.Errorf("last used = %v, want %v", got, keyNow)
+	}
+}
+
+func TestResolveAPIKeyRejects(t *testing.T) {
+	past := keyNow.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		mutate  func(*model.APIKey)
+		key     func(plaintext string) string
+		wantMsg string
+	}{
+		{
+			name:    "wrong secret",
+			key:     func(k string) string { return k[:len(k)-1] + "x" },
+			wantMsg: "invalid api key",
+		},
+		{
+			name:    "unknown lookup",
+			key:     func(string) string { return "gk_zzzzzzzz_secret" },
+			wantMsg: "invalid api key",
+		},
+		{
+			name:    "malformed",
+			key:     func(string) string { return "not-a-key" },
+			wantMsg: "invalid api key",
+		},
+		{
+			name:    "revoked",
+			mutate:  func(k *model.APIKey) { k.RevokedAt = &past },
+			wantMsg: "invalid api key",
+		},
+		{
+			name:    "expired",
+			mutate:  func(k *model.APIKey) { k.ExpiresAt = &past },
+			wantMsg: "api key expired",
+		},
+		{
+			name:    "expires this instant",
+			mutate:  func(k *model.APIKey) { k.ExpiresAt = &keyNow },
+			wantMsg: "api key expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, plaintext := newKeyFixture(t, tt.mutate)
+			if tt.key != nil {
+				plaintext = tt.key(plaintext)
+			}
+
+			_, err := svc.ResolveAPIKey(context.Background(), plaintext)
+
+			var appErr *e.AppError
+			if !errors.As(err, &appErr) || appErr.Code != e.CodeUnauthorized {
+				t.Fatalf("ResolveAPIKey() error = %v, want UNAUTHORIZED", err)
+			}
+			if appErr.Message != tt.wantMsg {
+				t.Errorf("message = %q, want %q", appErr.Message, tt.wantMsg)
+			}
+
+			svc.Close()
+			if len(repo.touched) != 0 {
+				t.Errorf("rejected key was marked used: %v", repo.touched)
+			}
+		})
+	}
+}
+
+// A repository failure is a server fault, not a bad credential.
+func TestResolveAPIKeyRepositoryError(t *testing.T) {
+	svc, repo, plaintext := newKeyFixture(t, nil)
+	repo.getErr = errors.New("connection refused")
+
+	_, err := svc.ResolveAPIKey(context.Background(), plaintext)
+
+	if got := e.From(err).Code; got != e.CodeInternal {
+		t.Errorf("code = %q, want %q", got, e.CodeInternal)
+	}
+}
+
+// Close is safe to call more than once, and usage after it is dropped.
+func TestAPIKeyServiceCloseIsIdempotent(t *testing.T) {
+	svc, repo, plaintext := newKeyFixture(t, nil)
+
+	svc.Close()
+	svc.Close()
+	if _, err := svc.ResolveAPIKey(context.Background(), plaintext); err != nil {
+		t.Fatalf("ResolveAPIKey() after Close error = %v", err)
+	}
+	if len(repo.touched) != 0 {
+		t.Errorf("usage after Close was recorded: %v", repo.touched)
+	}
+}
+
+func TestCreateAPIKeyLimitsScopesToCaller(t *testing.T) {
+	svc, repo, _ := newKeyFixture(t, nil)
+	caller := &auth.Principal{Kind: auth.KindAPIKey, Subject: "1", Scopes: []string{"users:*", "api_keys:manage"}}
+	ctx := auth.WithPrincipal(context.Background(), caller)
+
+	key, plaintext, err := svc.Create(ctx, &model.APIKey{Name: "reader", Scopes: []string{"users:read"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if plaintext == "" || repo.keys[key.Prefix] == nil {
+		t.Errorf("Create() did not store the key")
+	}
+
+	_, _, err = svc.Create(ctx, &model.APIKey{Name: "root", Scopes: []string{"*"}})
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) || appErr.Code != e.CodeForbidden {
+		t.Errorf("Create() with wider scope error = %v, want FORBIDDEN", err)
+	}
+}
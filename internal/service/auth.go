@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+	"github.com/aarondever/go-gin-template/internal/util"
+)
+
+// Tokens is what a successful login or refresh hands back.
+type Tokens struct {
+	AccessToken      string
+	ExpiresAt        time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// MFAChallenge stands in for tokens when the user has MFA on: answering it
+// with a code at VerifyMFA completes the login.
+type MFAChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// LoginResult holds either Tokens or, when a second factor is needed, a
+// Challenge.
+type LoginResult struct {
+	Tokens    *Tokens
+	Challenge *MFAChallenge
+}
+
+type AuthService interface {
+	// Login exchanges an email and password for tokens, or for a challenge
+	// if the user has MFA on. Every failure is the same UNAUTHORIZED error
+	// and takes about as long, so the response does not reveal whether the
+	// email is registered. Repeated failures for the email or from clientIP
+	// lock them out for a while with RATE_LIMITED.
+	Login(ctx context.Context, email, password, clientIP string) (*LoginResult, error)
+	// VerifyMFA completes a login challenged for a second factor. code is a
+	// TOTP code or an unused recovery code. A challenge can be answered once
+	// and until it expires. Wrong codes count towards the same lockout as
+	// wrong passwords.
+	VerifyMFA(ctx context.Context, challenge, code, clientIP string) (*Tokens, error)
+	// Refresh exchanges a refresh token for a new pair. Each refresh token
+	// works once; presenting one again revokes every token descended from the
+	// same login, since either the client or a thief is replaying it.
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	// Logout revokes the login refreshToken belongs to. Unknown tokens are
+	// ignored, so logging out twice is not an error.
+	Logout(ctx context.Context, refreshToken string) error
+	// LogoutAll revokes every refresh token of userID. Access tokens already
+	// issued stay valid until they expire.
+	LogoutAll(ctx context.Context, userID uint64) error
+	// Unlock forgets the failed logins of userID's email, lifting any
+	// lockout of the account. Lockouts of client IPs stay.
+	Unlock(ctx context.Context, userID uint64) error
+	// PurgeExpired deletes refresh tokens past their expiry and failed logins
+	// old enough to be forgotten.
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type authService struct {
+	users        repository.Repository
+	refresh      repository.RefreshTokenRepository
+	userTokens   repository.UserTokenRepository
+	tx           database.TxManager
+	passwords    *auth.PasswordHasher
+	tokens       *auth.TokenIssuer
+	mfa          *mfaCodes
+	guard        *loginGuard
+	refreshTTL   time.Duration
+	challengeTTL time.Duration
+	now          func() time.Time
+
+	// dummyHash is verified against when there is no real hash, so unknown
+	// emails cost the same argon2 work as wrong passwords.
+	dummyHash func() (string, error)
+}
+
+func NewAuthService(
+	users repository.Repository,
+	refresh repository.RefreshTokenRepository,
+	userTokens repository.UserTokenRepository,
+	recovery repository.RecoveryCodeRepository,
+	attempts repository.LoginAttemptRepository,
+	tx database.TxManager,
+	passwords *auth.PasswordHasher,
+	tokens *auth.TokenIssuer,
+	box *auth.SecretBox,
+	cfg config.AuthConfig,
+) AuthService {
+	return &authService{
+		users:        users,
+		refresh:      refresh,
+		userTokens:   userTokens,
+		tx:           tx,
+		passwords:    passwords,
+		tokens:       tokens,
+		mfa:          &mfaCodes{users: users, recovery: recovery, box: box},
+		guard:        newLoginGuard(attempts, cfg),
+		refreshTTL:   cfg.RefreshTokenTTL,
+		challengeTTL: cfg.MFAChallengeTTL,
+		now:          time.Now,
+		dummyHash: sync.OnceValues(func() (string, error) {
+			return passwords.Hash("not a real password")
+		}),
+	}
+}
+
+func errInvalidCredentials() error {
+	return e.New(e.CodeUnauthorized, "invalid email or password")
+}
+
+func errInvalidRefreshToken() error {
+	return e.New(e.CodeUnauthorized, "invalid refresh token")
+}
+
+func isNotFound(err error) bool {
+	var appErr *e.AppError
+	return errors.As(err, &appErr) && appErr.Code == e.CodeNotFound
+}
+
+func errInvalidChallenge() error {
+	return e.New(e.CodeUnauthorized, "invalid or expired challenge")
+}
+
+func (s *authService) Login(ctx context.Context, email, password, clientIP string) (*LoginResult, error) {
+	keys := newLoginKeys(email, clientIP)
+	if err := s.guard.check(ctx, keys, s.now()); err != nil {
+		return nil, fmt.Errorf("service.Login: %w", err)
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("service.Login: %w", err)
+	}
+
+	var encoded string
+	if user != nil && user.PasswordHash != nil {
+		encoded = *user.PasswordHash
+	} else {
+		if encoded, err = s.dummyHash(); err != nil {
+			return nil, fmt.Errorf("service.Login: %w", err)
+		}
+	}
+
+	match, rehash, err := s.passwords.Verify(password, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("service.Login: %w", err)
+	}
+	if !match || user == nil || user.PasswordHash == nil {
+		if err := s.guard.fail(ctx, keys, s.now()); err != nil {
+			return nil, fmt.Errorf("service.Login: %w", err)
+		}
+		return nil, errInvalidCredentials()
+	}
+
+	if rehash {
+		s.rehash(ctx, user.ID, password)
+	}
+
+	// Failures are forgotten only once the login is complete, so knowing the
+	// password does not buy more guesses at the second factor.
+	if user.MFAEnabledAt != nil {
+		challenge, err := s.challenge(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("service.Login: %w", err)
+		}
+		return &LoginResult{Challenge: challenge}, nil
+	}
+
+	tokens, err := s.issue(ctx, user.ID, util.NewID())
+	if err != nil {
+		return nil, fmt.Errorf("service.Login: %w", err)
+	}
+	s.guard.succeed(ctx, keys)
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// challenge records that user got the password right and now owes a second
+// factor.
+func (s *authService) challenge(ctx context.Context, user *model.User) (*MFAChallenge, error) {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	row := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.TokenMFAChallenge,
+		Hash:      auth.HashOpaqueToken(token),
+		Email:     *user.Email,
+		ExpiresAt: s.now().Add(s.challengeTTL),
+	}
+	if err := s.userTokens.Create(ctx, row); err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, challenge, code, clientIP string) (*Tokens, error) {
+	now := s.now()
+
+	var (
+		tokens *Tokens
+		keys   loginKeys
+		// wrongCode is set when code was wrong. The failure has to be counted
+		// outside the transaction, which returning an error rolls back.
+		wrongCode bool
+	)
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		t, err := s.userTokens.GetByHashForUpdate(ctx, model.TokenMFAChallenge, auth.HashOpaqueToken(challenge))
+		if err != nil {
+			if isNotFound(err) {
+				return errInvalidChallenge()
+			}
+			return err
+		}
+		if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+			return errInvalidChallenge()
+		}
+
+		keys = newLoginKeys(t.Email, clientIP)
+		if err := s.guard.check(ctx, keys, now); err != nil {
+			return err
+		}
+
+		user, err := s.users.GetByID(ctx, t.UserID)
+		if err != nil {
+			if isNotFound(err) {
+				return errInvalidChallenge()
+			}
+			return err
+		}
+		// MFA was turned off since the password was checked.
+		if user.MFAEnabledAt == nil {
+			return errInvalidChallenge()
+		}
+
+		ok, err := s.mfa.verify(ctx, user, code, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			wrongCode = true
+			return nil
+		}
+
+		if err := s.userTokens.MarkUsed(ctx, t.ID, now); err != nil {
+			return err
+		}
+		tokens, err = s.issue(ctx, user.ID, util.NewID())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service.VerifyMFA: %w", err)
+	}
+
+	if wrongCode {
+		if err := s.guard.fail(ctx, keys, now); err != nil {
+			return nil, fmt.Errorf("service.VerifyMFA: %w", err)
+		}
+		return nil, e.New(e.CodeUnauthorized, "invalid code")
+	}
+	s.guard.succeed(ctx, keys)
+	return tokens, nil
+}
+
+func (s *authService) rehash(ctx context.Context, userID uint64, password string) {
+	hash, err := s.passwords.Hash(password)
+	if err == nil {
+		err = s.users.Update(ctx, &model.User{ID: userID, PasswordHash: &hash})
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "rehash password", logger.Err(err))
+	}
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	now := s.now()
+
+	var (
+		tokens *Tokens
+		// revoked is why the family was just revoked. The revocation has to
+		// commit, so the rejection is returned after the transaction.
+		revoked string
+		current *model.RefreshToken
+	)
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		current, err = s.refresh.GetByHashForUpdate(ctx, auth.HashOpaqueToken(refreshToken))
+		if err != nil {
+			if isNotFound(err) {
+				return errInvalidRefreshToken()
+			}
+			return err
+		}
+
+		switch {
+		case current.RevokedAt != nil:
+			return errInvalidRefreshToken()
+		case current.UsedAt != nil:
+			revoked = "refresh token reused"
+			return s.refresh.RevokeFamily(ctx, current.FamilyID, now)
+		case !now.Before(current.ExpiresAt):
+			return e.New(e.CodeUnauthorized, "refresh token expired")
+		}
+
+		if _, err := s.users.GetByID(ctx, current.UserID); err != nil {
+			if isNotFound(err) {
+				revoked = "refresh token of deleted user"
+				return s.refresh.RevokeUser(ctx, current.UserID, now)
+			}
+			return err
+		}
+
+		if err := s.refresh.MarkUsed(ctx, current.ID, now); err != nil {
+			return err
+		}
+		tokens, err = s.issue(ctx, current.UserID, current.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service.Refresh: %w", err)
+	}
+
+	if revoked != "" {
+		logger.WarnContext(ctx, revoked+"; revoked",
+			slog.Uint64("user_id", current.UserID),
+			slog.String("family_id", current.FamilyID))
+		return nil, errInvalidRefreshToken()
+	}
+	return tokens, nil
+}
+
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		current, err := s.refresh.GetByHashForUpdate(ctx, auth.HashOpaqueToken(refreshToken))
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		return s.refresh.RevokeFamily(ctx, current.FamilyID, s.now())
+	})
+	if err != nil {
+		return fmt.Errorf("service.Logout: %w", err)
+	}
+	return nil
+}
+
+func (s *authService) LogoutAll(ctx context.Context, userID uint64) error {
+	if err := s.refresh.RevokeUser(ctx, userID, s.now()); err != nil {
+		return fmt.Errorf("service.LogoutAll: %w", err)
+	}
+	return nil
+}
+
+func (s *authService) Unlock(ctx context.Context, userID uint64) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service.Unlock: %w", err)
+	}
+	if user.Email == nil {
+		return nil
+	}
+	if err := s.guard.attempts.Reset(ctx, newLoginKeys(*user.Email, "").account); err != nil {
+		return fmt.Errorf("service.Unlock: %w", err)
+	}
+	return nil
+}
+
+func (s *authService) PurgeExpired(ctx context.Context) (int64, error) {
+	now := s.now()
+	tokens, err := s.refresh.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("service.PurgeExpired: %w", err)
+	}
+	attempts, err := s.guard.attempts.DeleteStale(ctx, now.Add(-s.guard.resetAfter))
+	if err != nil {
+		return tokens, fmt.Errorf("service.PurgeExpired: %w", err)
+	}
+	return tokens + attempts, nil
+}
+
+// issue mints an access token and a refresh token in family for userID.
+func (s *authService) issue(ctx context.Context, userID uint64, family string) (*Tokens, error) {
+	access, expiresAt, err := s.tokens.Issue(strconv.FormatUint(userID, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	row := &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		Hash:      auth.HashOpaqueToken(refresh),
+		ExpiresAt: s.now().Add(s.refreshTTL),
+	}
+	if err := s.refresh.Create(ctx, row); err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:      access,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: row.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeUserRepo serves users by email from memory, and records and applies
+// updates.
+type fakeUserRepo struct {
+	repository.Repository
+
+	byEmail map[string]*model.User
+	updated []*model.User
+	getErr  error
+}
+
+func (f *fakeUserRepo) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	user, ok := f.byEmail[email]
+	if !ok {
+		return nil, e.New(e.CodeNotFound, "user not found")
+	}
+	return user, nil
+}
+
+func (f *fakeUserRepo) GetByID(_ context.Context, userID uint64) (*model.User, error) {
+	if user := f.byID(userID); user != nil {
+		return user, nil
+	}
+	return nil, e.New(e.CodeNotFound, "user not found")
+}
+
+func (f *fakeUserRepo) Create(_ context.Context, user *model.User) error {
+	user.ID = uint64(len(f.byEmail) + 1)
+	f.byEmail[*user.Email] = user
+	return nil
+}
+
+func (f *fakeUserRepo) Update(_ context.Context, user *model.User) error {
+	f.updated = append(f.updated, user)
+	if stored := f.byID(user.ID); stored != nil {
+		if user.Email != nil {
+			stored.Email = user.Email
+		}
+		if user.PasswordHash != nil {
+			stored.PasswordHash = user.PasswordHash
+		}
+		if user.EmailVerifiedAt != nil {
+			stored.EmailVerifiedAt = user.EmailVerifiedAt
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserRepo) SetEmailVerified(_ context.Context, userID uint64, at *time.Time) error {
+	if stored := f.byID(userID); stored != nil {
+		stored.EmailVerifiedAt = at
+	}
+	return nil
+}
+
+func (f *fakeUserRepo) SetMFA(_ context.Context, userID uint64, secret *string, enabledAt *time.Time) error {
+	if stored := f.byID(userID); stored != nil {
+		stored.MFASecret, stored.MFAEnabledAt, stored.MFALastStep = secret, enabledAt, 0
+	}
+	return nil
+}
+
+func (f *fakeUserRepo) AdvanceMFAStep(_ context.Context, userID uint64, step int64) (bool, error) {
+	stored := f.byID(userID)
+	if stored == nil || stored.MFALastStep >= step {
+		return false, nil
+	}
+	stored.MFALastStep = step
+	return true, nil
+}
+
+func (f *fakeUserRepo) byID(userID uint64) *model.User {
+	for _, user := range f.byEmail {
+		if user.ID == userID {
+			return user
+		}
+	}
+	return nil
+}
+
+// fakeRefreshRepo keeps refresh tokens in memory.
+type fakeRefreshRepo struct {
+	repository.RefreshTokenRepository
+
+	byHash map[string]*model.RefreshToken
+	nextID uint64
+}
+
+func (f *fakeRefreshRepo) Create(_ context.Context, token *model.RefreshToken) error {
+	f.nextID++
+	token.ID = f.nextID
+	f.byHash[token.Hash] = token
+	return nil
+}
+
+func (f *fakeRefreshRepo) GetByHashForUpdate(_ context.Context, hash string) (*model.RefreshToken, error) {
+	token, ok := f.byHash[hash]
+	if !ok {
+		return nil, e.New(e.CodeNotFound, "refresh token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (f *fakeRefreshRepo) MarkUsed(_ context.Context, tokenID uint64, at time.Time) error {
+	f.each(func(t *model.RefreshToken) bool { return t.ID == tokenID }, func(t *model.RefreshToken) { t.UsedAt = &at })
+	return nil
+}
+
+func (f *fakeRefreshRepo) RevokeFamily(_ context.Context, familyID string, at time.Time) error {
+	f.each(func(t *model.RefreshToken) bool { return t.FamilyID == familyID }, func(t *model.RefreshToken) { t.RevokedAt = &at })
+	return nil
+}
+
+func (f *fakeRefreshRepo) RevokeUser(_ context.Context, userID uint64, at time.Time) error {
+	f.each(func(t *model.RefreshToken) bool { return t.UserID == userID }, func(t *model.RefreshToken) { t.RevokedAt = &at })
+	return nil
+}
+
+func (f *fakeRefreshRepo) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	var n int64
+	for hash, t := range f.byHash {
+		if t.ExpiresAt.Before(before) {
+			delete(f.byHash, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeRefreshRepo) each(match func(*model.RefreshToken) bool, apply func(*model.RefreshToken)) {
+	for _, t := range f.byHash {
+		if match(t) && t.RevokedAt == nil {
+			apply(t)
+		}
+	}
+}
+
+// active reports whether token would still be accepted.
+func (f *fakeRefreshRepo) active(token string) bool {
+	t, ok := f.byHash[auth.HashOpaqueToken(token)]
+	return ok && t.UsedAt == nil && t.RevokedAt == nil
+}
+
+// fakeTx runs fn without a transaction; the fakes have nothing to roll back.
+type fakeTx struct{}
+
+func (fakeTx) WithTx(ctx context.Context, fn func(ctx context.Context) error) error { return fn(ctx) }
+
+var testAuthConfig = config.AuthConfig{
+	JWTAlgorithm:      "HS256",
+	JWTSecret:         "0123456789abcdef0123456789abcdef",
+	AccessTokenTTL:    15 * time.Minute,
+	RefreshTokenTTL:   24 * time.Hour,
+	Argon2Memory:      64,
+	Argon2Iterations:  1,
+	Argon2Parallelism: 1,
+	MFAEncryptionKey:  "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+	MFAIssuer:         "Example",
+	MFAChallengeTTL:   5 * time.Minute,
+	AccountLockAfter:  5,
+	IPLockAfter:       20,
+	LockoutDuration:   time.Minute,
+	MaxLockout:        time.Hour,
+	LockoutResetAfter: 24 * time.Hour,
+}
+
+// testIP is the client every test logs in from.
+const testIP = "203.0.113.7"
+
+type authFixture struct {
+	svc        *authService
+	users      *fakeUserRepo
+	refresh    *fakeRefreshRepo
+	userTokens *fakeUserTokenRepo
+	recovery   *fakeRecoveryRepo
+	attempts   *fakeLoginAttemptRepo
+	passwords  *auth.PasswordHasher
+	box        *auth.SecretBox
+}
+
+func newAuthFixture(t *testing.T, cfg config.AuthConfig) *authFixture {
+	t.Helper()
+	passwords, err := auth.NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher() error = %v", err)
+	}
+	tokens, err := auth.NewTokenIssuer(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer() error = %v", err)
+	}
+	box, err := auth.NewSecretBox(cfg.MFAEncryptionKey)
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+	f := &authFixture{
+		users:      &fakeUserRepo{byEmail: map[string]*model.User{}},
+		refresh:    &fakeRefreshRepo{byHash: map[string]*model.RefreshToken{}},
+		userTokens: &fakeUserTokenRepo{byHash: map[string]*model.UserToken{}},
+		recovery:   &fakeRecoveryRepo{},
+		attempts:   &fakeLoginAttemptRepo{byKey: map[string]*model.LoginAttempt{}},
+		passwords:  passwords,
+		box:        box,
+	}
+	f.svc = NewAuthService(
+		f.users, f.refresh, f.userTokens, f.recovery, f.attempts, fakeTx{}, passwords, tokens, box, cfg,
+	).(*authService)
+	return f
+}
+
+func addUser(t *testing.T, repo *fakeUserRepo, h *auth.PasswordHasher, id uint64, email, password string) {
+	t.Helper()
+	user := &model.User{ID: id, Email: &email}
+	if password != "" {
+		hash, err := h.Hash(password)
+		if err != nil {
+			t.Fatalf("Hash() error = %v", err)
+		}
+		user.PasswordHash = &hash
+	}
+	repo.byEmail[email] = user
+}
+
+func TestLogin(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	addUser(t, f.users, f.passwords, 42, "ada@example.com", "correct horse battery")
+
+	result, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if result.Challenge != nil {
+		t.Fatal("Login() challenged a user without MFA")
+	}
+
+	verifier, err := auth.NewJWTVerifier(testAuthConfig)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	p, err := verifier.Verify(result.Tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if p.Subject != "42" {
+		t.Errorf("subject = %q, want 42", p.Subject)
+	}
+	if len(f.users.updated) != 0 {
+		t.Errorf("hash rewritten %d times for a current hash", len(f.users.updated))
+	}
+}
+
+// Every way a login can fail reads the same, so callers cannot probe for
+// registered emails.
+func TestLoginFailuresAreIndistinguishable(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	addUser(t, f.users, f.passwords, 1, "ada@example.com", "correct horse battery")
+	addUser(t, f.users, f.passwords, 2, "nopass@example.com", "")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{name: "wrong password", email: "ada@example.com", password: "wrong horse battery"},
+		{name: "unknown email", email: "nobody@example.com", password: "correct horse battery"},
+		{name: "user without password", email: "nopass@example.com", password: "not a real password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.svc.Login(context.Background(), tt.email, tt.password, testIP)
+			var appErr *e.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("Login() error = %v, want *AppError", err)
+			}
+			if appErr.Code != e.CodeUnauthorized || appErr.Message != "invalid email or password" {
+				t.Errorf("Login() error = %s %q, want UNAUTHORIZED %q", appErr.Code, appErr.Message, "invalid email or password")
+			}
+		})
+	}
+}
+
+func TestLoginRehashesOutdatedHash(t *testing.T) {
+	old, err := auth.NewPasswordHasher(testAuthConfig)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher() error = %v", err)
+	}
+	stronger := testAuthConfig
+	stronger.Argon2Iterations = 2
+	f := newAuthFixture(t, stronger)
+	addUser(t, f.users, old, 42, "ada@example.com", "correct horse battery")
+
+	if _, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	updated := f.users.updated
+	if len(updated) != 1 || updated[0].ID != 42 || updated[0].PasswordHash == nil {
+		t.Fatalf("updates = %+v, want one new hash for user 42", updated)
+	}
+	if _, rehash, _ := f.passwords.Verify("correct horse battery", *updated[0].PasswordHash); rehash {
+		t.Error("stored hash still uses the old parameters")
+	}
+}
+
+func TestLoginRepositoryError(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	cause := errors.New("db down")
+	f.users.getErr = cause
+
+	if _, err := f.svc.Login(context.Background(), "ada@example.com", "pw", testIP); !errors.Is(err, cause) {
+		t.Errorf("Login() error = %v, want %v", err, cause)
+	}
+}
+
+// login signs in a fresh user 42 and returns its tokens.
+func login(t *testing.T, f *authFixture) *Tokens {
+	t.Helper()
+	addUser(t, f.users, f.passwords, 42, "ada@example.com", "correct horse battery")
+	result, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return result.Tokens
+}
+
+func assertRefreshRejected(t *testing.T, err error, wantMsg string) {
+	t.Helper()
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) || appErr.Code != e.CodeUnauthorized || appErr.Message != wantMsg {
+		t.Errorf("Refresh() error = %v, want UNAUTHORIZED %q", err, wantMsg)
+	}
+}
+
+func TestRefreshRotates(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	first := login(t, f)
+
+	second, err := f.svc.Refresh(context.Background(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Errorf("Refresh() = %+v, want a new token pair", second)
+	}
+	if f.refresh.active(first.RefreshToken) {
+		t.Error("old refresh token still usable after rotation")
+	}
+	if !f.refresh.active(second.RefreshToken) {
+		t.Error("new refresh token not usable")
+	}
+
+	a := f.refresh.byHash[auth.HashOpaqueToken(first.RefreshToken)]
+	b := f.refresh.byHash[auth.HashOpaqueToken(second.RefreshToken)]
+	if a.FamilyID != b.FamilyID {
+		t.Errorf("rotated token family = %q, want %q", b.FamilyID, a.FamilyID)
+	}
+}
+
+// Replaying a used token means someone else may hold the chain: everything
+// descended from that login dies, including the legitimately rotated token.
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	first := login(t, f)
+	second, err := f.svc.Refresh(context.Background(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	_, err = f.svc.Refresh(context.Background(), first.RefreshToken)
+	assertRefreshRejected(t, err, "invalid refresh token")
+
+	if f.refresh.active(second.RefreshToken) {
+		t.Error("descendant token survived reuse of its ancestor")
+	}
+	_, err = f.svc.Refresh(context.Background(), second.RefreshToken)
+	assertRefreshRejected(t, err, "invalid refresh token")
+}
+
+func TestRefreshRejects(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	tokens := login(t, f)
+
+	_, err := f.svc.Refresh(context.Background(), "not-a-token")
+	assertRefreshRejected(t, err, "invalid refresh token")
+
+	f.svc.now = func() time.Time { return time.Now().Add(testAuthConfig.RefreshTokenTTL + time.Minute) }
+	_, err = f.svc.Refresh(context.Background(), tokens.RefreshToken)
+	assertRefreshRejected(t, err, "refresh token expired")
+}
+
+func TestRefreshDeletedUser(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	tokens := login(t, f)
+	delete(f.users.byEmail, "ada@example.com")
+
+	_, err := f.svc.Refresh(context.Background(), tokens.RefreshToken)
+	assertRefreshRejected(t, err, "invalid refresh token")
+	if f.refresh.active(tokens.RefreshToken) {
+		t.Error("token of deleted user still active")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	tokens := login(t, f)
+	other, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if err := f.svc.Logout(context.Background(), tokens.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if f.refresh.active(tokens.RefreshToken) {
+		t.Error("token still active after logout")
+	}
+	if !f.refresh.active(other.Tokens.RefreshToken) {
+		t.Error("logout ended another session")
+	}
+
+	if err := f.svc.Logout(context.Background(), "unknown"); err != nil {
+		t.Errorf("Logout(unknown) error = %v, want nil", err)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	a := login(t, f)
+	b, _ := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+
+	if err := f.svc.LogoutAll(context.Background(), 42); err != nil {
+		t.Fatalf("LogoutAll() error = %v", err)
+	}
+	if f.refresh.active(a.RefreshToken) || f.refresh.active(b.Tokens.RefreshToken) {
+		t.Error("a session survived LogoutAll")
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	f := newAuthFixture(t, testAuthConfig)
+	login(t, f)
+
+	if n, _ := f.svc.PurgeExpired(context.Background()); n != 0 {
+		t.Errorf("PurgeExpired() = %d, want 0 before expiry", n)
+	}
+	f.svc.now = func() time.Time { return time.Now().Add(testAuthConfig.RefreshTokenTTL + time.Minute) }
+	if n, _ := f.svc.PurgeExpired(context.Background()); n != 1 {
+		t.Errorf("PurgeExpired() = %d, want 1 after expiry", n)
+	}
+}
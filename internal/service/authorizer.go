@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// permissionCacheTTL bounds how long a role change takes to reach a user who
+// is already signed in.
+const permissionCacheTTL = 30 * time.Second
+
+// ownerActions are the actions a user may always perform on resources they own,
+// whatever their roles: users may read and update themselves.
+var ownerActions = map[auth.Permission]string{
+	auth.PermUsersRead:  auth.ResourceUser,
+	auth.PermUsersWrite: auth.ResourceUser,
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expires     time.Time
+}
+
+type authorizer struct {
+	roles repository.RoleRepository
+	now   func() time.Time
+
+	mu    sync.Mutex
+	cache map[uint64]cachedPermissions
+}
+
+// NewAuthorizer checks users against the permissions of their roles, and API
+// keys against their scopes. A user token that carries a scope claim is
+// limited to what both its roles and its scope allow.
+func NewAuthorizer(roles repository.RoleRepository) auth.Authorizer {
+	return &authorizer{
+		roles: roles,
+		now:   time.Now,
+		cache: make(map[uint64]cachedPermissions),
+	}
+}
+
+func (a *authorizer) Authorize(ctx context.Context, action auth.Permission, resource *auth.Resource) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return e.New(e.CodeUnauthorized, "authentication required")
+	}
+
+	allowed, err := a.allowed(ctx, principal, action, resource)
+	if err != nil {
+		return fmt.Errorf("authorize %s: %w", action, err)
+	}
+	if !allowed {
+		return e.New(e.CodeForbidden, "permission denied").
+			WithDetails(map[string]string{"permission": string(action)})
+	}
+	return nil
+}
+
+func (a *authorizer) allowed(
+	ctx context.Context,
+	principal *auth.Principal,
+	action auth.Permission,
+	resource *auth.Resource,
+) (bool, error) {
+	switch principal.Kind {
+	case auth.KindSystem:
+		return true, nil
+
+	case auth.KindAPIKey:
+		return auth.Grants(principal.Scopes, action), nil
+
+	case auth.KindUser:
+		// A scoped token can only narrow what the user may do.
+		if len(principal.Scopes) > 0 && !auth.Grants(principal.Scopes, action) {
+			return false, nil
+		}
+		if resource != nil && ownerActions[action] == resource.Type && resource.OwnerID == principal.Subject {
+			return true, nil
+		}
+
+		userID, err := strconv.ParseUint(principal.Subject, 10, 64)
+		if err != nil {
+			return false, nil
+		}
+		permissions, err := a.permissions(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		return auth.Grants(permissions, action), nil
+	}
+	return false, nil
+}
+
+func (a *authorizer) permissions(ctx context.Context, userID uint64) ([]string, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[userID]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.permissions, nil
+	}
+
+	roles, err := a.roles.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Sweep on write so users who stop calling do not accumulate.
+	for id, c := range a.cache {
+		if !now.Before(c.expires) {
+			delete(a.cache, id)
+		}
+	}
+	a.cache[userID] = cachedPermissions{permissions: permissions, expires: now.Add(permissionCacheTTL)}
+	return permissions, nil
+}
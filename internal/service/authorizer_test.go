@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeRoleRepo serves roles from memory and counts lookups.
+type fakeRoleRepo struct {
+	repository.RoleRepository
+
+	roles map[uint64][]*model.Role
+	calls int
+	err   error
+}
+
+func (f *fakeRoleRepo) GetByUserID(_ context.Context, userID uint64) ([]*model.Role, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.roles[userID], nil
+}
+
+func TestAuthorize(t *testing.T) {
+	roles := &fakeRoleRepo{roles: map[uint64][]*model.Role{
+		1: {{Name: "admin", Permissions: []string{"*"}}},
+		2: {{Name: "support", Permissions: []string{"users:read"}}},
+	}}
+	az := NewAuthorizer(roles)
+
+	user := func(id string, scopes ...string) *auth.Principal {
+		return &auth.Principal{Kind: auth.KindUser, Subject: id, Scopes: scopes}
+	}
+	self := func(id string) *auth.Resource { return &auth.Resource{Type: auth.ResourceUser, OwnerID: id} }
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		action    auth.Permission
+		resource  *auth.Resource
+		wantCode  e.Code // empty means allowed
+	}{
+		{name: "admin role", principal: user("1"), action: auth.PermUsersWrite},
+		{name: "role grants action", principal: user("2"), action: auth.PermUsersRead},
+		{name: "role lacks action", principal: user("2"), action: auth.PermUsersWrite, wantCode: e.CodeForbidden},
+		{name: "no roles", principal: user("3"), action: auth.PermUsersRead, wantCode: e.CodeForbidden},
+		{name: "owner updates self", principal: user("3"), action: auth.PermUsersWrite, resource: self("3")},
+		{
+			name:      "owner rule does not cover others",
+			principal: user("3"),
+			action:    auth.PermUsersWrite,
+			resource:  self("4"),
+			wantCode:  e.CodeForbidden,
+		},
+		{
+			name:      "owner rule does not cover other actions",
+			principal: user("3"),
+			action:    auth.PermAPIKeysManage,
+			resource:  self("3"),
+			wantCode:  e.CodeForbidden,
+		},
+		{
+			name:      "token scope narrows roles",
+			principal: user("1", "users:read"),
+			action:    auth.PermUsersWrite,
+			wantCode:  e.CodeForbidden,
+		},
+		{name: "token scope within roles", principal: user("1", "users:*"), action: auth.PermUsersWrite},
+		{
+			name:      "api key scope",
+			principal: &auth.Principal{Kind: auth.KindAPIKey, Subject: "9", Scopes: []string{"users:read"}},
+			action:    auth.PermUsersRead,
+		},
+		{
+			name:      "api key never owns a user",
+			principal: &auth.Principal{Kind: auth.KindAPIKey, Subject: "3"},
+			action:    auth.PermUsersWrite,
+			resource:  self("3"),
+			wantCode:  e.CodeForbidden,
+		},
+		{name: "system", principal: auth.System, action: auth.PermAPIKeysManage},
+		{name: "unauthenticated", action: auth.PermUsersRead, wantCode: e.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			err := az.Authorize(ctx, tt.action, tt.resource)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("Authorize() error = %v, want nil", err)
+				}
+				return
+			}
+			var appErr *e.AppError
+			if !errors.As(err, &appErr) || appErr.Code != tt.wantCode {
+				t.Errorf("Authorize() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAuthorizeDenialNamesPermission(t *testing.T) {
+	az := NewAuthorizer(&fakeRoleRepo{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, Subject: "1"})
+
+	err := az.Authorize(ctx, auth.PermUsersWrite, nil)
+
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Authorize() error = %v, want *AppError", err)
+	}
+	if got := appErr.Details["permission"]; got != "users:write" {
+		t.Errorf("details[permission] = %q, want %q", got, "users:write")
+	}
+}
+
+func TestAuthorizeCachesRoles(t *testing.T) {
+	roles := &fakeRoleRepo{roles: map[uint64][]*model.Role{1: {{Permissions: []string{"users:read"}}}}}
+	az := NewAuthorizer(roles).(*authorizer)
+	now := keyNow
+	az.now = func() time.Time { return now }
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, Subject: "1"})
+
+	for range 3 {
+		if err := az.Authorize(ctx, auth.PermUsersRead, nil); err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+	}
+	if roles.calls != 1 {
+		t.Errorf("role lookups = %d, want 1 while cached", roles.calls)
+	}
+
+	now = now.Add(permissionCacheTTL)
+	if err := az.Authorize(ctx, auth.PermUsersRead, nil); err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if roles.calls != 2 {
+		t.Errorf("role lookups = %d, want 2 after expiry", roles.calls)
+	}
+}
+
+func TestAuthorizeRepositoryError(t *testing.T) {
+	cause := errors.New("db down")
+	az := NewAuthorizer(&fakeRoleRepo{err: cause})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, Subject: "1"})
+
+	if err := az.Authorize(ctx, auth.PermUsersRead, nil); !errors.Is(err, cause) {
+		t.Errorf("Authorize() error = %v, want %v", err, cause)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/logger"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// loginGuard slows down password and MFA code guessing. It counts failures
+// per account and per client IP, and locks either out once it crosses its
+// threshold, for twice as long with each failure after that.
+type loginGuard struct {
+	attempts     repository.LoginAttemptRepository
+	accountAfter int
+	ipAfter      int
+	lockout      time.Duration
+	maxLockout   time.Duration
+	resetAfter   time.Duration
+}
+
+func newLoginGuard(attempts repository.LoginAttemptRepository, cfg config.AuthConfig) *loginGuard {
+	return &loginGuard{
+		attempts:     attempts,
+		accountAfter: cfg.AccountLockAfter,
+		ipAfter:      cfg.IPLockAfter,
+		lockout:      cfg.LockoutDuration,
+		maxLockout:   cfg.MaxLockout,
+		resetAfter:   cfg.LockoutResetAfter,
+	}
+}
+
+// loginKeys names what a login attempt is counted against. Unknown emails
+// are counted like known ones, so lockouts do not reveal who has an account.
+type loginKeys struct {
+	account string
+	ip      string
+}
+
+func newLoginKeys(email, clientIP string) loginKeys {
+	k := loginKeys{account: "email:" + strings.ToLower(strings.TrimSpace(email))}
+	if clientIP != "" {
+		k.ip = "ip:" + clientIP
+	}
+	return k
+}
+
+// accountKeys names the account user's own codes are counted against when
+// checked without a login: its email's, so they share one lockout, or else
+// its ID's.
+func accountKeys(user *model.User) loginKeys {
+	if user.Email != nil {
+		return newLoginKeys(*user.Email, "")
+	}
+	return loginKeys{account: "user:" + strconv.FormatUint(user.ID, 10)}
+}
+
+func (k loginKeys) all() []string {
+	if k.ip == "" {
+		return []string{k.account}
+	}
+	return []string{k.account, k.ip}
+}
+
+func errLockedOut(retryAfter time.Duration) error {
+	return e.New(e.CodeRateLimited, "too many failed attempts").WithRetryAfter(retryAfter)
+}
+
+// check fails with RATE_LIMITED while any of keys is locked.
+func (g *loginGuard) check(ctx context.Context, keys loginKeys, now time.Time) error {
+	attempts, err := g.attempts.Get(ctx, keys.all()...)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	for _, a := range attempts {
+		if a.LockedUntil != nil && a.LockedUntil.After(until) {
+			until = *a.LockedUntil
+		}
+	}
+	if until.After(now) {
+		return errLockedOut(until.Sub(now))
+	}
+	return nil
+}
+
+// fail counts a failure against keys and locks those over their threshold,
+// returning RATE_LIMITED if it locked any.
+func (g *loginGuard) fail(ctx context.Context, keys loginKeys, now time.Time) error {
+	var locked time.Duration
+	for _, key := range keys.all() {
+		threshold := g.accountAfter
+		if key == keys.ip {
+			threshold = g.ipAfter
+		}
+
+		a, err := g.attempts.RecordFailure(ctx, key, now, now.Add(-g.resetAfter))
+		if err != nil {
+			return err
+		}
+		if threshold <= 0 || a.Failures < threshold {
+			continue
+		}
+
+		d := g.lockoutAfter(a.Failures - threshold)
+		if err := g.attempts.Lock(ctx, key, now.Add(d)); err != nil {
+			return err
+		}
+		logger.WarnContext(ctx, "login locked out",
+			slog.String("key", key),
+			slog.Int("failures", a.Failures),
+			slog.Duration("duration", d))
+		locked = max(locked, d)
+	}
+
+	if locked > 0 {
+		return errLockedOut(locked)
+	}
+	return nil
+}
+
+// lockoutAfter is how long to lock a key that is over its threshold by over
+// failures.
+func (g *loginGuard) lockoutAfter(over int) time.Duration {
+	d := g.lockout
+	for range over {
+		if d >= g.maxLockout/2 {
+			return g.maxLockout
+		}
+		d *= 2
+	}
+	return min(d, g.maxLockout)
+}
+
+// succeed forgets the account's failures. The client IP's stay: otherwise
+// logging into an account of one's own would reset the count between guesses
+// at others.
+func (g *loginGuard) succeed(ctx context.Context, keys loginKeys) {
+	if err := g.attempts.Reset(ctx, keys.account); err != nil {
+		logger.WarnContext(ctx, "reset login failures", logger.Err(err))
+	}
+}
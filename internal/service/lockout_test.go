@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeLoginAttemptRepo keeps failure counts in memory.
+type fakeLoginAttemptRepo struct {
+	repository.LoginAttemptRepository
+
+	byKey map[string]*model.LoginAttempt
+}
+
+func (f *fakeLoginAttemptRepo) Get(_ context.Context, keys ...string) ([]*model.LoginAttempt, error) {
+	var attempts []*model.LoginAttempt
+	for _, key := range keys {
+		if a, ok := f.byKey[key]; ok {
+			copied := *a
+			attempts = append(attempts, &copied)
+		}
+	}
+	return attempts, nil
+}
+
+func (f *fakeLoginAttemptRepo) RecordFailure(_ context.Context, key string, at, resetBefore time.Time) (*model.LoginAttempt, error) {
+	a, ok := f.byKey[key]
+	if !ok || a.LastFailureAt.Before(resetBefore) {
+		a = &model.LoginAttempt{Key: key}
+		f.byKey[key] = a
+	}
+	a.Failures++
+	a.LastFailureAt = at
+	copied := *a
+	return &copied, nil
+}
+
+func (f *fakeLoginAttemptRepo) Lock(_ context.Context, key string, until time.Time) error {
+	f.byKey[key].LockedUntil = &until
+	return nil
+}
+
+func (f *fakeLoginAttemptRepo) Reset(_ context.Context, key string) error {
+	delete(f.byKey, key)
+	return nil
+}
+
+func (f *fakeLoginAttemptRepo) DeleteStale(_ context.Context, before time.Time) (int64, error) {
+	var n int64
+	for key, a := range f.byKey {
+		if a.LastFailureAt.Before(before) {
+			delete(f.byKey, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// assertLockedOut checks err is RATE_LIMITED with the given Retry-After.
+func assertLockedOut(t *testing.T, err error, retryAfter time.Duration) {
+	t.Helper()
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) || appErr.Code != e.CodeRateLimited {
+		t.Fatalf("error = %v, want RATE_LIMITED", err)
+	}
+	if appErr.RetryAfter != retryAfter {
+		t.Errorf("RetryAfter = %v, want %v", appErr.RetryAfter, retryAfter)
+	}
+}
+
+// clockedAuthFixture is an auth fixture with user 42 on a clock the test
+// moves.
+func clockedAuthFixture(t *testing.T, cfg config.AuthConfig) (*authFixture, *time.Time) {
+	t.Helper()
+	f := newAuthFixture(t, cfg)
+	now := time.Date(2026, 8, 18, 10, 0, 0, 0, time.UTC)
+	f.svc.now = func() time.Time { return now }
+	addUser(t, f.users, f.passwords, 42, "ada@example.com", "correct horse battery")
+	return f, &now
+}
+
+func TestLoginLocksAccount(t *testing.T) {
+	f, now := clockedAuthFixture(t, testAuthConfig)
+	login := func(password string) error {
+		_, err := f.svc.Login(context.Background(), "ada@example.com", password, testIP)
+		return err
+	}
+
+	for i := 1; i < testAuthConfig.AccountLockAfter; i++ {
+		assertAppError(t, login("wrong"), e.CodeUnauthorized, "invalid email or password")
+	}
+	assertLockedOut(t, login("wrong"), time.Minute)
+
+	// Locked means locked, right password or not.
+	*now = now.Add(20 * time.Second)
+	assertLockedOut(t, login("correct horse battery"), 40*time.Second)
+
+	// Each failure past the threshold doubles the lock.
+	*now = now.Add(40 * time.Second)
+	assertLockedOut(t, login("wrong"), 2*time.Minute)
+	*now = now.Add(2 * time.Minute)
+	assertLockedOut(t, login("wrong"), 4*time.Minute)
+
+	*now = now.Add(4 * time.Minute)
+	if err := login("correct horse battery"); err != nil {
+		t.Fatalf("Login() after the lock = %v", err)
+	}
+	// Success starts the count over.
+	assertAppError(t, login("wrong"), e.CodeUnauthorized, "invalid email or password")
+}
+
+// Unknown emails lock like real ones, so a lockout reveals nothing.
+func TestLoginLocksUnknownEmail(t *testing.T) {
+	f, _ := clockedAuthFixture(t, testAuthConfig)
+
+	var err error
+	for range testAuthConfig.AccountLockAfter {
+		_, err = f.svc.Login(context.Background(), "nobody@example.com", "wrong", testIP)
+	}
+	assertLockedOut(t, err, time.Minute)
+}
+
+func TestLoginLocksClientIP(t *testing.T) {
+	cfg := testAuthConfig
+	cfg.IPLockAfter = 3
+	f, _ := clockedAuthFixture(t, cfg)
+
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		_, err := f.svc.Login(context.Background(), email, "wrong", testIP)
+		assertAppError(t, err, e.CodeUnauthorized, "invalid email or password")
+	}
+	_, err := f.svc.Login(context.Background(), "c@example.com", "wrong", testIP)
+	assertLockedOut(t, err, time.Minute)
+
+	// Every account is locked for that client, and only for that client.
+	_, err = f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	assertLockedOut(t, err, time.Minute)
+	if _, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", "198.51.100.1"); err != nil {
+		t.Errorf("Login() from another IP = %v", err)
+	}
+}
+
+func TestLoginForgetsOldFailures(t *testing.T) {
+	f, now := clockedAuthFixture(t, testAuthConfig)
+
+	for range testAuthConfig.AccountLockAfter - 1 {
+		_, _ = f.svc.Login(context.Background(), "ada@example.com", "wrong", testIP)
+	}
+	*now = now.Add(testAuthConfig.LockoutResetAfter + time.Second)
+
+	_, err := f.svc.Login(context.Background(), "ada@example.com", "wrong", testIP)
+	assertAppError(t, err, e.CodeUnauthorized, "invalid email or password")
+
+	if n, _ := f.svc.PurgeExpired(context.Background()); n != 0 {
+		t.Errorf("PurgeExpired() = %d, want the fresh failure kept", n)
+	}
+	*now = now.Add(testAuthConfig.LockoutResetAfter + time.Second)
+	if n, _ := f.svc.PurgeExpired(context.Background()); n != 2 {
+		t.Errorf("PurgeExpired() = %d, want the account and IP rows", n)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	f, _ := clockedAuthFixture(t, testAuthConfig)
+	for range testAuthConfig.AccountLockAfter {
+		_, _ = f.svc.Login(context.Background(), "ada@example.com", "wrong", testIP)
+	}
+
+	if err := f.svc.Unlock(context.Background(), 42); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if _, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP); err != nil {
+		t.Errorf("Login() after Unlock = %v", err)
+	}
+
+	assertAppError(t, f.svc.Unlock(context.Background(), 7), e.CodeNotFound, "user not found")
+}
+
+// Wrong second factors count too, and getting the password right again does
+// not reset the count.
+func TestVerifyMFALocksAccount(t *testing.T) {
+	f := newMFAFixture(t)
+	f.enroll(t)
+
+	for i := 1; i < testAuthConfig.AccountLockAfter; i++ {
+		_, err := f.svc.VerifyMFA(context.Background(), f.challenge(t), "000000", testIP)
+		assertAppError(t, err, e.CodeUnauthorized, "invalid code")
+	}
+	_, err := f.svc.VerifyMFA(context.Background(), f.challenge(t), "000000", testIP)
+	assertLockedOut(t, err, time.Minute)
+
+	_, err = f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	assertLockedOut(t, err, time.Minute)
+}
+
+// A stolen session cannot guess the code that turns MFA off, or the one that
+// confirms an enrollment, any faster than a login's.
+func TestDisableMFALocksAccount(t *testing.T) {
+	f := newMFAFixture(t)
+	_, codes := f.enroll(t)
+
+	for i := 1; i < testAuthConfig.AccountLockAfter; i++ {
+		assertAppError(t, f.mfa.Disable(asUser("42"), 42, "000000"), e.CodeInvalidInput, "invalid code")
+	}
+	assertLockedOut(t, f.mfa.Disable(asUser("42"), 42, "000000"), time.Minute)
+
+	// Now even a right code is turned away.
+	assertLockedOut(t, f.mfa.Disable(asUser("42"), 42, codes[0]), time.Minute)
+	if user, _ := f.users.GetByID(context.Background(), 42); user.MFAEnabledAt == nil {
+		t.Error("MFA turned off while locked out")
+	}
+}
+
+func TestConfirmTOTPLocksAccount(t *testing.T) {
+	f := newMFAFixture(t)
+	if _, err := f.mfa.EnrollTOTP(context.Background(), 42); err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+
+	for i := 1; i < testAuthConfig.AccountLockAfter; i++ {
+		_, err := f.mfa.ConfirmTOTP(context.Background(), 42, "000000")
+		assertAppError(t, err, e.CodeInvalidInput, "invalid code")
+	}
+	_, err := f.mfa.ConfirmTOTP(context.Background(), 42, "000000")
+	assertLockedOut(t, err, time.Minute)
+
+	_, err = f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	assertLockedOut(t, err, time.Minute)
+}
+
+func TestLockoutAfterIsCapped(t *testing.T) {
+	g := newLoginGuard(nil, testAuthConfig)
+
+	tests := []struct {
+		over int
+		want time.Duration
+	}{
+		{over: 0, want: time.Minute},
+		{over: 1, want: 2 * time.Minute},
+		{over: 5, want: 32 * time.Minute},
+		{over: 6, want: time.Hour},
+		{over: 1000, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := g.lockoutAfter(tt.over); got != tt.want {
+			t.Errorf("lockoutAfter(%d) = %v, want %v", tt.over, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarondever/go-gin-template/config"
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/database"
+	"github.com/aarondever/go-gin-template/internal/model"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// TOTPEnrollment is what an authenticator app needs to start producing codes.
+type TOTPEnrollment struct {
+	Secret string // base32, for typing in by hand
+	URI    string // otpauth://, for a QR code
+}
+
+type MFAService interface {
+	// EnrollTOTP gives userID a new TOTP secret. MFA is not on until
+	// ConfirmTOTP sees a code made with it; enrolling again before then
+	// replaces the secret.
+	EnrollTOTP(ctx context.Context, userID uint64) (*TOTPEnrollment, error)
+	// ConfirmTOTP turns MFA on once code proves the authenticator holds the
+	// secret, and returns fresh recovery codes. They are shown only this once.
+	// Wrong codes count towards the account's login lockout.
+	ConfirmTOTP(ctx context.Context, userID uint64, code string) ([]string, error)
+	// Disable turns MFA off and forgets the secret and recovery codes. Users
+	// turning off their own MFA prove they still hold it with code, a TOTP or
+	// recovery code, so a stolen session cannot, and wrong codes count towards
+	// the account's login lockout; others, such as an administrator resetting
+	// a lost authenticator, need none.
+	Disable(ctx context.Context, userID uint64, code string) error
+}
+
+type mfaService struct {
+	users    repository.Repository
+	recovery repository.RecoveryCodeRepository
+	tx       database.TxManager
+	guard    *loginGuard
+	codes    *mfaCodes
+	issuer   string
+	now      func() time.Time
+}
+
+func NewMFAService(
+	users repository.Repository,
+	recovery repository.RecoveryCodeRepository,
+	attempts repository.LoginAttemptRepository,
+	tx database.TxManager,
+	box *auth.SecretBox,
+	cfg config.AuthConfig,
+) MFAService {
+	return &mfaService{
+		users:    users,
+		recovery: recovery,
+		tx:       tx,
+		guard:    newLoginGuard(attempts, cfg),
+		codes:    &mfaCodes{users: users, recovery: recovery, box: box},
+		issuer:   cfg.MFAIssuer,
+		now:      time.Now,
+	}
+}
+
+func errMFAEnabled() error {
+	return e.New(e.CodeConflict, "mfa already enabled")
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID uint64) (*TOTPEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.EnrollTOTP: %w", err)
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errMFAEnabled()
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("service.EnrollTOTP: %w", err)
+	}
+	sealed, err := s.codes.box.Seal(secret, userContext(userID))
+	if err != nil {
+		return nil, fmt.Errorf("service.EnrollTOTP: %w", err)
+	}
+	if err := s.users.SetMFA(ctx, userID, &sealed, nil); err != nil {
+		return nil, fmt.Errorf("service.EnrollTOTP: %w", err)
+	}
+
+	account := strconv.FormatUint(userID, 10)
+	if user.Email != nil {
+		account = *user.Email
+	}
+	return &TOTPEnrollment{
+		Secret: auth.EncodeTOTPSecret(secret),
+		URI:    auth.TOTPKeyURI(s.issuer, account, secret),
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID uint64, code string) ([]string, error) {
+	now := s.now()
+	var (
+		recoveryCodes []string
+		keys          loginKeys
+		// As in VerifyMFA, a failure is counted after the transaction.
+		wrongCode bool
+	)
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabledAt != nil {
+			return errMFAEnabled()
+		}
+		if user.MFASecret == nil {
+			return e.New(e.CodeInvalidInput, "no mfa enrollment in progress")
+		}
+
+		keys = accountKeys(user)
+		if err := s.guard.check(ctx, keys, now); err != nil {
+			return err
+		}
+		secret, err := s.codes.secret(user)
+		if err != nil {
+			return err
+		}
+		step, ok := auth.ValidateTOTP(secret, code, now)
+		if !ok {
+			wrongCode = true
+			return nil
+		}
+
+		if err := s.users.SetMFA(ctx, userID, user.MFASecret, &now); err != nil {
+			return err
+		}
+		// The confirming code must not also work for a login.
+		if _, err := s.users.AdvanceMFAStep(ctx, userID, step); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = auth.GenerateRecoveryCodes()
+		if err != nil {
+			return err
+		}
+		hashes := make([]string, len(recoveryCodes))
+		for i, c := range recoveryCodes {
+			hashes[i] = auth.HashRecoveryCode(c)
+		}
+		return s.recovery.Replace(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service.ConfirmTOTP: %w", err)
+	}
+
+	if wrongCode {
+		if err := s.guard.fail(ctx, keys, now); err != nil {
+			return nil, fmt.Errorf("service.ConfirmTOTP: %w", err)
+		}
+		return nil, errInvalidMFACode()
+	}
+	s.guard.succeed(ctx, keys)
+	return recoveryCodes, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID uint64, code string) error {
+	now := s.now()
+	var (
+		keys      loginKeys
+		wrongCode bool
+	)
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabledAt != nil && isSelf(ctx, userID) {
+			if strings.TrimSpace(code) == "" {
+				return e.New(e.CodeInvalidInput, "mfa code required").
+					WithViolations([]e.Violation{{Path: "/code", Rule: "required", Message: "is required"}})
+			}
+			keys = accountKeys(user)
+			if err := s.guard.check(ctx, keys, now); err != nil {
+				return err
+			}
+			ok, err := s.codes.verify(ctx, user, code, now)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				wrongCode = true
+				return nil
+			}
+		}
+		if err := s.users.SetMFA(ctx, userID, nil, nil); err != nil {
+			return err
+		}
+		return s.recovery.DeleteUser(ctx, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("service.DisableMFA: %w", err)
+	}
+
+	if wrongCode {
+		if err := s.guard.fail(ctx, keys, now); err != nil {
+			return fmt.Errorf("service.DisableMFA: %w", err)
+		}
+		return errInvalidMFACode()
+	}
+	if keys.account != "" {
+		s.guard.succeed(ctx, keys)
+	}
+	return nil
+}
+
+func errInvalidMFACode() error {
+	return e.New(e.CodeInvalidInput, "invalid code")
+}
+
+// isSelf reports whether the caller is the user userID.
+func isSelf(ctx context.Context, userID uint64) bool {
+	p, ok := auth.PrincipalFrom(ctx)
+	return ok && p.Kind == auth.KindUser && p.Subject == strconv.FormatUint(userID, 10)
+}
+
+// userContext binds a sealed secret to the user it belongs to.
+func userContext(userID uint64) []byte {
+	return strconv.AppendUint([]byte("user:"), userID, 10)
+}
+
+// mfaCodes checks second-factor codes. Login and enrollment share it.
+type mfaCodes struct {
+	users    repository.Repository
+	recovery repository.RecoveryCodeRepository
+	box      *auth.SecretBox
+}
+
+func (m *mfaCodes) secret(user *model.User) ([]byte, error) {
+	return m.box.Open(*user.MFASecret, userContext(user.ID))
+}
+
+// verify reports whether code is a TOTP code of user at time at, or one of
+// their unused recovery codes, and uses it up either way.
+func (m *mfaCodes) verify(ctx context.Context, user *model.User, code string, at time.Time) (bool, error) {
+	code = strings.TrimSpace(code)
+	if !auth.IsTOTPCode(code) {
+		return m.recovery.Use(ctx, user.ID, auth.HashRecoveryCode(code), at)
+	}
+
+	secret, err := m.secret(user)
+	if err != nil {
+		return false, err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, at)
+	if !ok {
+		return false, nil
+	}
+	return m.users.AdvanceMFAStep(ctx, user.ID, step)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	e "github.com/aarondever/go-gin-template/internal/apperror"
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/repository"
+)
+
+// fakeRecoveryRepo keeps recovery code hashes in memory; used ones are
+// removed.
+type fakeRecoveryRepo struct {
+	repository.RecoveryCodeRepository
+
+	byUser map[uint64]map[string]bool
+}
+
+func (f *fakeRecoveryRepo) Replace(_ context.Context, userID uint64, hashes []string) error {
+	if f.byUser == nil {
+		f.byUser = map[uint64]map[string]bool{}
+	}
+	f.byUser[userID] = map[string]bool{}
+	for _, h := range hashes {
+		f.byUser[userID][h] = true
+	}
+	return nil
+}
+
+func (f *fakeRecoveryRepo) Use(_ context.Context, userID uint64, hash string, _ time.Time) (bool, error) {
+	if !f.byUser[userID][hash] {
+		return false, nil
+	}
+	delete(f.byUser[userID], hash)
+	return true, nil
+}
+
+func (f *fakeRecoveryRepo) DeleteUser(_ context.Context, userID uint64) error {
+	delete(f.byUser, userID)
+	return nil
+}
+
+// mfaFixture is an auth fixture plus an MFA service sharing its fakes, all
+// on a clock the test moves.
+type mfaFixture struct {
+	*authFixture
+	mfa *mfaService
+	now time.Time
+}
+
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+	f := &mfaFixture{
+		authFixture: newAuthFixture(t, testAuthConfig),
+		now:         time.Date(2026, 8, 18, 10, 0, 0, 0, time.UTC),
+	}
+	f.mfa = NewMFAService(f.users, f.recovery, f.attempts, fakeTx{}, f.box, testAuthConfig).(*mfaService)
+	clock := func() time.Time { return f.now }
+	f.svc.now, f.mfa.now = clock, clock
+	addUser(t, f.users, f.passwords, 42, "ada@example.com", "correct horse battery")
+	return f
+}
+
+// enroll turns MFA on for user 42 and returns its TOTP key and recovery
+// codes.
+func (f *mfaFixture) enroll(t *testing.T) ([]byte, []string) {
+	t.Helper()
+	enrollment, err := f.mfa.EnrollTOTP(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	codes, err := f.mfa.ConfirmTOTP(context.Background(), 42, f.code(secret))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+	return secret, codes
+}
+
+// code is the TOTP code of secret at the fixture's clock.
+func (f *mfaFixture) code(secret []byte) string {
+	return auth.TOTPCode(secret, auth.TOTPStep(f.now))
+}
+
+// challenge logs user 42 in and returns the MFA challenge it gets.
+func (f *mfaFixture) challenge(t *testing.T) string {
+	t.Helper()
+	result, err := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if result.Challenge == nil || result.Tokens != nil {
+		t.Fatalf("Login() = %+v, want only a challenge", result)
+	}
+	return result.Challenge.Token
+}
+
+func assertAppError(t *testing.T, err error, code e.Code, msg string) {
+	t.Helper()
+	var appErr *e.AppError
+	if !errors.As(err, &appErr) || appErr.Code != code || appErr.Message != msg {
+		t.Errorf("error = %v, want %s %q", err, code, msg)
+	}
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	f := newMFAFixture(t)
+
+	enrollment, err := f.mfa.EnrollTOTP(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	user, _ := f.users.GetByID(context.Background(), 42)
+	if user.MFASecret == nil || user.MFAEnabledAt != nil {
+		t.Fatalf("after enroll: secret %v, enabled %v; want pending", user.MFASecret, user.MFAEnabledAt)
+	}
+	if *user.MFASecret == enrollment.Secret {
+		t.Error("secret stored in the clear")
+	}
+	want := "otpauth://totp/Example:ada@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&secret=" + enrollment.Secret
+	if enrollment.URI != want {
+		t.Errorf("URI = %q, want %q", enrollment.URI, want)
+	}
+
+	// Pending enrollment does not change how login works.
+	if result, _ := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP); result.Tokens == nil {
+		t.Error("pending enrollment challenged the login")
+	}
+
+	secret, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	assertAppError(t, func() error {
+		_, err := f.mfa.ConfirmTOTP(context.Background(), 42, "000000")
+		return err
+	}(), e.CodeInvalidInput, "invalid code")
+
+	codes, err := f.mfa.ConfirmTOTP(context.Background(), 42, f.code(secret))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+	if len(codes) != auth.RecoveryCodeCount || len(f.recovery.byUser[42]) != auth.RecoveryCodeCount {
+		t.Errorf("got %d codes, stored %d", len(codes), len(f.recovery.byUser[42]))
+	}
+	if user.MFAEnabledAt == nil || !user.MFAEnabledAt.Equal(f.now) {
+		t.Errorf("MFAEnabledAt = %v, want %v", user.MFAEnabledAt, f.now)
+	}
+
+	_, err = f.mfa.EnrollTOTP(context.Background(), 42)
+	assertAppError(t, err, e.CodeConflict, "mfa already enabled")
+}
+
+func TestConfirmTOTPWithoutEnrollment(t *testing.T) {
+	f := newMFAFixture(t)
+
+	_, err := f.mfa.ConfirmTOTP(context.Background(), 42, "123456")
+	assertAppError(t, err, e.CodeInvalidInput, "no mfa enrollment in progress")
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	f := newMFAFixture(t)
+	secret, _ := f.enroll(t)
+
+	// The code that confirmed enrollment cannot be replayed to log in.
+	_, err := f.svc.VerifyMFA(context.Background(), f.challenge(t), f.code(secret), testIP)
+	assertAppError(t, err, e.CodeUnauthorized, "invalid code")
+
+	f.now = f.now.Add(30 * time.Second)
+	challenge := f.challenge(t)
+	tokens, err := f.svc.VerifyMFA(context.Background(), challenge, f.code(secret), testIP)
+	if err != nil {
+		t.Fatalf("VerifyMFA() error = %v", err)
+	}
+	if tokens.AccessToken == "" || !f.refresh.active(tokens.RefreshToken) {
+		t.Errorf("VerifyMFA() = %+v, want usable tokens", tokens)
+	}
+
+	// A challenge completes one login.
+	f.now = f.now.Add(30 * time.Second)
+	_, err = f.svc.VerifyMFA(context.Background(), challenge, f.code(secret), testIP)
+	assertAppError(t, err, e.CodeUnauthorized, "invalid or expired challenge")
+}
+
+func TestLoginWithRecoveryCode(t *testing.T) {
+	f := newMFAFixture(t)
+	_, codes := f.enroll(t)
+
+	if _, err := f.svc.VerifyMFA(context.Background(), f.challenge(t), " "+codes[3]+" ", testIP); err != nil {
+		t.Fatalf("VerifyMFA(recovery code) error = %v", err)
+	}
+
+	_, err := f.svc.VerifyMFA(context.Background(), f.challenge(t), codes[3], testIP)
+	assertAppError(t, err, e.CodeUnauthorized, "invalid code")
+	if len(f.recovery.byUser[42]) != auth.RecoveryCodeCount-1 {
+		t.Errorf("%d codes left, want %d", len(f.recovery.byUser[42]), auth.RecoveryCodeCount-1)
+	}
+}
+
+func TestVerifyMFARejectsChallenge(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *mfaFixture, challenge string) string
+	}{
+		{name: "unknown", setup: func(*mfaFixture, string) string { return "unknown" }},
+		{name: "expired", setup: func(f *mfaFixture, challenge string) string {
+			f.now = f.now.Add(testAuthConfig.MFAChallengeTTL)
+			return challenge
+		}},
+		{name: "mfa disabled since", setup: func(f *mfaFixture, challenge string) string {
+			if err := f.mfa.Disable(context.Background(), 42, ""); err != nil {
+				t.Fatalf("Disable() error = %v", err)
+			}
+			return challenge
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMFAFixture(t)
+			secret, _ := f.enroll(t)
+			challenge := tt.setup(f, f.challenge(t))
+
+			f.now = f.now.Add(30 * time.Second)
+			_, err := f.svc.VerifyMFA(context.Background(), challenge, f.code(secret), testIP)
+			assertAppError(t, err, e.CodeUnauthorized, "invalid or expired challenge")
+		})
+	}
+}
+
+// asUser is the context of a request made by the user userID.
+func asUser(userID string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, Subject: userID})
+}
+
+func TestDisableMFA(t *testing.T) {
+	f := newMFAFixture(t)
+	secret, _ := f.enroll(t)
+
+	f.now = f.now.Add(30 * time.Second)
+	if err := f.mfa.Disable(asUser("42"), 42, f.code(secret)); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	user, _ := f.users.GetByID(context.Background(), 42)
+	if user.MFASecret != nil || user.MFAEnabledAt != nil || f.recovery.byUser[42] != nil {
+		t.Errorf("MFA state left after Disable: %+v, codes %v", user, f.recovery.byUser[42])
+	}
+	if result, _ := f.svc.Login(context.Background(), "ada@example.com", "correct horse battery", testIP); result.Tokens == nil {
+		t.Error("login still challenged after Disable")
+	}
+}
+
+func TestDisableMFAWithRecoveryCode(t *testing.T) {
+	f := newMFAFixture(t)
+	_, codes := f.enroll(t)
+
+	if err := f.mfa.Disable(asUser("42"), 42, codes[0]); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if user, _ := f.users.GetByID(context.Background(), 42); user.MFAEnabledAt != nil {
+		t.Error("MFA still on")
+	}
+}
+
+func TestDisableOwnMFARequiresCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantMsg string
+	}{
+		{name: "missing", code: " ", wantMsg: "mfa code required"},
+		{name: "wrong", code: "000000", wantMsg: "invalid code"},
+		{name: "unknown recovery code", code: "aaaa-bbbb-cccc-dddd", wantMsg: "invalid code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMFAFixture(t)
+			f.enroll(t)
+
+			err := f.mfa.Disable(asUser("42"), 42, tt.code)
+			assertAppError(t, err, e.CodeInvalidInput, tt.wantMsg)
+			if user, _ := f.users.GetByID(context.Background(), 42); user.MFAEnabledAt == nil {
+				t.Error("MFA turned off without a valid code")
+			}
+		})
+	}
+}
+
+// An administrator resets the MFA of a user who lost their authenticator.
+func TestDisableOthersMFAWithoutCode(t *testing.T) {
+	f := newMFAFixture(t)
+	f.enroll(t)
+
+	if err := f.mfa.Disable(asUser("1"), 42, ""); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if user, _ := f.users.GetByID(context.Background(), 42); user.MFAEnabledAt != nil {
+		t.Error("MFA still on")
+	}
+}
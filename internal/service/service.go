@@ -4,28 +4,42 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/aarondever/go-gin-template/internal/auth"
+	"github.com/aarondever/go-gin-template/internal/database"
 	"github.com/aarondever/go-gin-template/internal/model"
 	p "github.com/aarondever/go-gin-template/internal/pagination"
 	"github.com/aarondever/go-gin-template/internal/repository"
 )
 
 type Service interface {
-	Create(ctx context.Context, user *model.User) (*model.User, error)
+	// Create stores user; a non-empty password is hashed and lets them log in.
+	Create(ctx context.Context, user *model.User, password string) (*model.User, error)
 	GetByID(ctx context.Context, userID uint64) (*model.User, error)
 	GetList(ctx context.Context, page *p.Pagination, filter *model.UserListFilter) ([]*model.User, error)
+	// Update stores user's non-zero fields. A changed email is unverified
+	// until a link sent to it is followed.
 	Update(ctx context.Context, user *model.User) (*model.User, error)
 	Delete(ctx context.Context, userID uint64) error
 }
 
 type service struct {
-	repo repository.Repository
+	repo      repository.Repository
+	tx        database.TxManager
+	passwords *auth.PasswordHasher
 }
 
-func New(repo repository.Repository) Service {
-	return &service{repo: repo}
+func New(repo repository.Repository, tx database.TxManager, passwords *auth.PasswordHasher) Service {
+	return &service{repo: repo, tx: tx, passwords: passwords}
 }
 
-func (s *service) Create(ctx context.Context, user *model.User) (*model.User, error) {
+func (s *service) Create(ctx context.Context, user *model.User, password string) (*model.User, error) {
+	if password != "" {
+		hash, err := s.passwords.Hash(password)
+		if err != nil {
+			return nil, fmt.Errorf("service.Create: %w", err)
+		}
+		user.PasswordHash = &hash
+	}
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("service.Create: %w", err)
 	}
@@ -53,7 +67,23 @@ func (s *service) GetList(
 }
 
 func (s *service) Update(ctx context.Context, user *model.User) (*model.User, error) {
-	if err := s.repo.Update(ctx, user); err != nil {
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if user.Email == nil {
+			return s.repo.Update(ctx, user)
+		}
+		current, err := s.repo.GetByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		changed := current.Email == nil || *current.Email != *user.Email
+		if err := s.repo.Update(ctx, user); err != nil || !changed {
+			return err
+		}
+		// Verification proved the old address, not this one.
+		user.EmailVerifiedAt = nil
+		return s.repo.SetEmailVerified(ctx, user.ID, nil)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("service.Update: %w", err)
 	}
 	return user, nil
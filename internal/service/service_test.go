@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aarondever/go-gin-template/internal/model"
+)
+
+func newVerifiedUser(email string) *model.User {
+	verifiedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &model.User{ID: 1, Name: "Ada", Email: &email, EmailVerifiedAt: &verifiedAt}
+}
+
+func TestUpdateEmailUnverifies(t *testing.T) {
+	users := &fakeUserRepo{byEmail: map[string]*model.User{"ada@example.com": newVerifiedUser("ada@example.com")}}
+	svc := New(users, fakeTx{}, nil)
+
+	email := "ada@example.org"
+	got, err := svc.Update(context.Background(), &model.User{ID: 1, Name: "Ada", Email: &email})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.EmailVerifiedAt != nil {
+		t.Errorf("returned EmailVerifiedAt = %v, want nil", got.EmailVerifiedAt)
+	}
+	stored, _ := users.GetByID(context.Background(), 1)
+	if *stored.Email != email {
+		t.Errorf("stored email = %q, want %q", *stored.Email, email)
+	}
+	if stored.EmailVerifiedAt != nil {
+		t.Errorf("stored EmailVerifiedAt = %v, want nil after the email changed", stored.EmailVerifiedAt)
+	}
+}
+
+func TestUpdateSameEmailStaysVerified(t *testing.T) {
+	users := &fakeUserRepo{byEmail: map[string]*model.User{"ada@example.com": newVerifiedUser("ada@example.com")}}
+	svc := New(users, fakeTx{}, nil)
+
+	email := "ada@example.com"
+	if _, err := svc.Update(context.Background(), &model.User{ID: 1, Name: "Ada L.", Email: &email}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stored, _ := users.GetByID(context.Background(), 1); stored.EmailVerifiedAt == nil {
+		t.Error("stored EmailVerifiedAt = nil, want it kept when the email is unchanged")
+	}
+}
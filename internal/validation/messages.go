@@ -0,0 +1,162 @@
+package validation
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultLocale is the language of rule messages for locales without a
+// translation.
+const DefaultLocale = "en"
+
+// ruleMessages phrase broken rules for people, without naming the field: the
+// violation's path does that. {0} is the rule's parameter. Size rules have a
+// message per unit they count, suffixed _chars, _items or _value; "default"
+// covers rules without a message of their own, with {0} the rule.
+var ruleMessages = map[string]map[string]string{
+	"en": {
+		"required":  "is required",
+		"email":     "must be a valid email address",
+		"password":  "must be {0} to {1} characters mixing at least two of lower case, upper case, digits and symbols",
+		"numeric":   "must contain only digits",
+		"oneof":     "must be one of {0}",
+		"len_chars": "must be exactly {0} characters",
+		"len_items": "must be exactly {0} items",
+		"len_value": "must be exactly {0}",
+		"min_chars": "must be at least {0} characters",
+		"min_items": "must be at least {0} items",
+		"min_value": "must be at least {0}",
+		"max_chars": "must be at most {0} characters",
+		"max_items": "must be at most {0} items",
+		"max_value": "must be at most {0}",
+		"gt_chars":  "must be more than {0} characters",
+		"gt_items":  "must be more than {0} items",
+		"gt_value":  "must be more than {0}",
+		"gt_now":    "must be in the future",
+		"lt_chars":  "must be less than {0} characters",
+		"lt_items":  "must be less than {0} items",
+		"lt_value":  "must be less than {0}",
+		"lt_now":    "must be in the past",
+		"default":   "must satisfy {0}",
+	},
+	"es": {
+		"required":  "es obligatorio",
+		"email":     "debe ser una dirección de correo electrónico válida",
+		"password":  "debe tener entre {0} y {1} caracteres y combinar al menos dos de: minúsculas, mayúsculas, dígitos y símbolos",
+		"numeric":   "solo puede contener dígitos",
+		"oneof":     "debe ser uno de {0}",
+		"len_chars": "debe tener exactamente {0} caracteres",
+		"len_items": "debe tener exactamente {0} elementos",
+		"len_value": "debe ser exactamente {0}",
+		"min_chars": "debe tener al menos {0} caracteres",
+		"min_items": "debe tener al menos {0} elementos",
+		"min_value": "debe ser como mínimo {0}",
+		"max_chars": "debe tener como máximo {0} caracteres",
+		"max_items": "debe tener como máximo {0} elementos",
+		"max_value": "debe ser como máximo {0}",
+		"gt_chars":  "debe tener más de {0} caracteres",
+		"gt_items":  "debe tener más de {0} elementos",
+		"gt_value":  "debe ser mayor que {0}",
+		"gt_now":    "debe estar en el futuro",
+		"lt_chars":  "debe tener menos de {0} caracteres",
+		"lt_items":  "debe tener menos de {0} elementos",
+		"lt_value":  "debe ser menor que {0}",
+		"lt_now":    "debe estar en el pasado",
+		"default":   "debe cumplir la regla {0}",
+	},
+}
+
+// ruleAliases share another rule's messages.
+var ruleAliases = map[string]string{
+	"gte":              "min",
+	"lte":              "max",
+	"number":           "numeric",
+	"required_if":      "required",
+	"required_unless":  "required",
+	"required_with":    "required",
+	"required_without": "required",
+}
+
+var translators = newTranslators()
+
+func newTranslators() *ut.UniversalTranslator {
+	supported := map[string]locales.Translator{"en": en.New(), "es": es.New()}
+	uni := ut.New(supported[DefaultLocale])
+	for locale, messages := range ruleMessages {
+		if err := uni.AddTranslator(supported[locale], true); err != nil {
+			panic(err)
+		}
+		trans, _ := uni.GetTranslator(locale)
+		for key, text := range messages {
+			// Only fails on a malformed message, which is a programming error.
+			if err := trans.Add(key, text, false); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return uni
+}
+
+// Locales lists the languages rule messages are written in.
+func Locales() []string {
+	out := make([]string, 0, len(ruleMessages))
+	for locale := range ruleMessages {
+		out = append(out, locale)
+	}
+	return out
+}
+
+// Message phrases the rule fe broke for people, in locale's language, or in
+// [DefaultLocale]'s when locale has no translation.
+func Message(fe validator.FieldError, locale string) string {
+	if _, ok := ruleMessages[locale]; !ok {
+		locale = DefaultLocale
+	}
+	trans, _ := translators.GetTranslator(locale)
+
+	key, params := messageKey(fe)
+	if msg, err := trans.T(key, params...); err == nil {
+		return msg
+	}
+	rule := fe.Tag()
+	if fe.Param() != "" {
+		rule += "=" + fe.Param()
+	}
+	msg, _ := trans.T("default", rule)
+	return msg
+}
+
+func messageKey(fe validator.FieldError) (string, []string) {
+	rule := fe.Tag()
+	if alias, ok := ruleAliases[rule]; ok {
+		rule = alias
+	}
+	param := fe.Param()
+	switch rule {
+	case "password":
+		return rule, []string{strconv.Itoa(PasswordMinLen), strconv.Itoa(PasswordMaxLen)}
+	case "oneof":
+		return rule, []string{strings.Join(strings.Fields(param), ", ")}
+	case "gt", "lt":
+		if param == "" { // on a time: compared with now
+			return rule + "_now", nil
+		}
+	case "len", "min", "max":
+	default:
+		return rule, []string{param}
+	}
+	switch fe.Kind() {
+	case reflect.String:
+		return rule + "_chars", []string{param}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rule + "_items", []string{param}
+	}
+	return rule + "_value", []string{param}
+}
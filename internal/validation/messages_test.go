@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestMessage(t *testing.T) {
+	type req struct {
+		Name  string   `json:"name" validate:"min=3"`
+		Tags  []string `json:"tags" validate:"max=1"`
+		Email string   `json:"email" validate:"required"`
+		Code  string   `json:"code" validate:"uuid"`
+	}
+	err := ValidateStruct(req{Name: "ab", Tags: []string{"a", "b"}})
+	var ve validator.ValidationErrors
+	if !errors.As(err, &ve) || len(ve) != 4 {
+		t.Fatalf("ValidateStruct() error = %v, want 4 validation errors", err)
+	}
+
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"en", []string{"must be at least 3 characters", "must be at most 1 items", "is required", "must satisfy uuid"}},
+		{"es", []string{"debe tener al menos 3 caracteres", "debe tener como máximo 1 elementos", "es obligatorio", "debe cumplir la regla uuid"}},
+		// No translation: English.
+		{"fr", []string{"must be at least 3 characters", "must be at most 1 items", "is required", "must satisfy uuid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			for i, fe := range ve {
+				if got := Message(fe, tt.locale); got != tt.want[i] {
+					t.Errorf("Message(%s, %q) = %q, want %q", fe.Tag(), tt.locale, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// A rule phrased in English but not in another language would silently fall
+// back to "must satisfy <rule>" there.
+func TestRuleMessagesTranslateEveryRule(t *testing.T) {
+	for locale, messages := range ruleMessages {
+		for key := range ruleMessages[DefaultLocale] {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("locale %s has no message %q", locale, key)
+			}
+		}
+		if len(messages) != len(ruleMessages[DefaultLocale]) {
+			t.Errorf("locale %s has %d messages, want %d", locale, len(messages), len(ruleMessages[DefaultLocale]))
+		}
+	}
+}
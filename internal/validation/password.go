@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// PasswordMinLen and PasswordMaxLen bound a password in characters. The
+	// upper bound keeps a hash request from being arbitrarily expensive.
+	PasswordMinLen = 12
+	PasswordMaxLen = 128
+	// passwordMinClasses is how many of lower case, upper case, digits and
+	// everything else a password must mix.
+	passwordMinClasses = 2
+)
+
+// IsStrongPassword reports whether s satisfies the "password" rule: between
+// [PasswordMinLen] and [PasswordMaxLen] characters, drawn from at least two
+// character classes.
+func IsStrongPassword(s string) bool {
+	if n := utf8.RuneCountInString(s); n < PasswordMinLen || n > PasswordMaxLen {
+		return false
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			classes++
+		}
+	}
+	return classes >= passwordMinClasses
+}
+
+func validatePassword(fl validator.FieldLevel) bool {
+	return IsStrongPassword(fl.Field().String())
+}
@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestIsStrongPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "letters and digits", password: "correcthorse42", want: true},
+		{name: "mixed case", password: "CorrectHorseBattery", want: true},
+		{name: "passphrase with spaces", password: "correct horse battery", want: true},
+		{name: "non-ascii counts as characters", password: "pässwörter-über", want: true},
+		{name: "too short", password: "Short1!", want: false},
+		{name: "one class", password: "correcthorsebattery", want: false},
+		{name: "digits only", password: "123456789012", want: false},
+		{name: "too long", password: strings.Repeat("aB", PasswordMaxLen/2+1), want: false},
+		{name: "empty", password: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStrongPassword(tt.password); got != tt.want {
+				t.Errorf("IsStrongPassword(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStructPasswordRule(t *testing.T) {
+	type req struct {
+		Password string `json:"password" validate:"password"`
+	}
+
+	if err := ValidateStruct(req{Password: "correcthorse42"}); err != nil {
+		t.Errorf("ValidateStruct(strong) error = %v", err)
+	}
+
+	err := ValidateStruct(req{Password: "weak"})
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("ValidateStruct(weak) error = %v, want one ValidationError", err)
+	}
+	if errs[0].Field() != "password" || errs[0].Tag() != "password" {
+		t.Errorf("error on %s/%s, want password/password", errs[0].Field(), errs[0].Tag())
+	}
+}
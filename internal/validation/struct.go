@@ -12,13 +12,18 @@ var validate = newValidator()
 func newValidator() *validator.Validate {
 	v := validator.New(validator.WithRequiredStructEnabled())
 	v.RegisterTagNameFunc(FieldName)
+	// Only fails on a duplicate or malformed tag, which is a programming error.
+	if err := v.RegisterValidation("password", validatePassword); err != nil {
+		panic(err)
+	}
 	return v
 }
 
 // FieldName reports the name a struct field is given in validation errors: its
-// json tag, then its form tag, falling back to the Go field name.
+// json tag, then its form, uri, query or header tag, falling back to the Go
+// field name.
 func FieldName(fld reflect.StructField) string {
-	for _, tag := range []string{"json", "form"} {
+	for _, tag := range []string{"json", "form", "uri", "query", "header"} {
 		name, _, _ := strings.Cut(fld.Tag.Get(tag), ",")
 		if name == "-" {
 			return ""
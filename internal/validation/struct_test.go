@@ -29,6 +29,9 @@ func TestFieldName(t *testing.T) {
 		JSONEmpty    string `json:",omitempty" form:"fallback_form"`
 		SkipWinsOver string `json:"-" form:"form_name"`
 		FormSkipped  string `form:"-"`
+		URIOnly      string `uri:"uri_only"`
+		QueryOnly    string `query:"query_only"`
+		HeaderOnly   string `header:"X-Header"`
 		Untagged     string
 		OtherTag     string `validate:"required" db:"other"`
 	}
@@ -47,6 +50,9 @@ func TestFieldName(t *testing.T) {
 		{name: "empty json name falls through to form", field: "JSONEmpty", want: "fallback_form"},
 		{name: "json dash wins over form", field: "SkipWinsOver", want: ""},
 		{name: "form dash is skipped", field: "FormSkipped", want: ""},
+		{name: "uri tag", field: "URIOnly", want: "uri_only"},
+		{name: "query tag", field: "QueryOnly", want: "query_only"},
+		{name: "header tag", field: "HeaderOnly", want: "X-Header"},
 		{name: "untagged field", field: "Untagged", want: ""},
 		{name: "unrelated tags are ignored", field: "OtherTag", want: ""},
 	}